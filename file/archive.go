@@ -0,0 +1,530 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrUnsafeArchivePath is reported when an archive entry would end up outside the destination dir on extraction,
+// either by its name (absolute or containing "..") or by being a link pointing outside
+var ErrUnsafeArchivePath = errors.New("unsafe path in archive")
+
+// ErrUnknownArchiveFormat is reported when the archive format can't be derived from the file name
+var ErrUnknownArchiveFormat = errors.New("unknown archive format")
+
+// ArchiveFormat selects the archive type to create or extract
+type ArchiveFormat int
+
+const (
+	// ArchiveAuto derives the format from the archive's file extension: ".tar", ".tar.gz", ".tgz" or ".zip"
+	ArchiveAuto ArchiveFormat = iota
+	ArchiveTar
+	ArchiveTarGz
+	ArchiveZip
+)
+
+// ArchiveOptions configures CreateArchive and ExtractArchive. The filter is applied to the paths in the source dir
+// when creating, and to the entry names when extracting.
+type ArchiveOptions struct {
+	FilterOptions
+	Format ArchiveFormat
+}
+
+// CreateArchive packs the tree below srcDirPath into a tar, tar.gz or zip archive. Entry names are relative to
+// srcDirPath and use "/" as separator. Permissions and modification times are stored, symlinks are stored as links,
+// special files are skipped. The archive itself is left out if it is placed below srcDirPath. A partially written
+// archive is removed on failure.
+func CreateArchive(srcDirPath, archivePath string, options ArchiveOptions) (err error) {
+	format, err := archiveFormat(archivePath, options.Format)
+	if err != nil {
+		return wrapError("archive", archivePath, err)
+	}
+	filter, err := NewFilter(options.FilterOptions)
+	if err != nil {
+		return wrapError("archive", srcDirPath, err)
+	}
+	if _, err = os.Stat(srcDirPath); err != nil {
+		return wrapError("archive", srcDirPath, err)
+	}
+	archiveFileRef, err := os.Create(archivePath)
+	if err != nil {
+		return wrapError("archive", archivePath, err)
+	}
+	defer func() {
+		if closeErr := archiveFileRef.Close(); err == nil && closeErr != nil {
+			err = wrapError("archive", archivePath, closeErr)
+		}
+		if err != nil {
+			_ = os.Remove(archivePath)
+		}
+	}()
+	archiveInfo, err := archiveFileRef.Stat()
+	if err != nil {
+		return wrapError("archive", archivePath, err)
+	}
+
+	writer := newArchiveWriter(archiveFileRef, format)
+	err = walkFiltered(srcDirPath, filter, func(entryPath, relPath string, dirEntry fs.DirEntry) error {
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		if os.SameFile(info, archiveInfo) {
+			return nil
+		}
+		switch {
+		case info.IsDir():
+			return writer.addDir(relPath, info)
+		case info.Mode()&os.ModeSymlink != 0:
+			linkTarget, err := os.Readlink(entryPath)
+			if err != nil {
+				return err
+			}
+			return writer.addSymlink(relPath, linkTarget, info)
+		case info.Mode().IsRegular():
+			return writer.addFile(relPath, entryPath, info)
+		}
+		return nil
+	})
+	if err != nil {
+		return wrapError("archive", srcDirPath, err)
+	}
+	return wrapError("archive", archivePath, writer.close())
+}
+
+// ExtractArchive unpacks a tar, tar.gz or zip archive into dstDirPath, creating it if needed. Entries which would end
+// up outside dstDirPath, links pointing outside and entries placed behind a symlink fail the extraction with
+// ErrUnsafeArchivePath. Permissions and modification times are
+// restored, existing files are overwritten. Entry types other than dirs, regular files, symlinks and (tar) hard links
+// are skipped.
+func ExtractArchive(archivePath, dstDirPath string, options ArchiveOptions) error {
+	format, err := archiveFormat(archivePath, options.Format)
+	if err != nil {
+		return wrapError("extract", archivePath, err)
+	}
+	filter, err := NewFilter(options.FilterOptions)
+	if err != nil {
+		return wrapError("extract", archivePath, err)
+	}
+	if err = os.MkdirAll(dstDirPath, 0755); err != nil {
+		return wrapError("extract", dstDirPath, err)
+	}
+
+	root, err := os.OpenRoot(dstDirPath)
+	if err != nil {
+		return wrapError("extract", dstDirPath, err)
+	}
+	defer root.Close()
+
+	extractor := &archiveExtractor{dstDirPath: dstDirPath, root: root, filter: filter}
+	if format == ArchiveZip {
+		err = extractor.extractZip(archivePath)
+	} else {
+		err = extractor.extractTar(archivePath, format == ArchiveTarGz)
+	}
+	if err == nil {
+		err = extractor.restoreDirs()
+	}
+	return wrapError("extract", archivePath, err)
+}
+
+// archiveFormat resolves ArchiveAuto by the file extension
+func archiveFormat(archivePath string, format ArchiveFormat) (ArchiveFormat, error) {
+	if format != ArchiveAuto {
+		return format, nil
+	}
+	name := strings.ToLower(filepath.Base(archivePath))
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveTarGz, nil
+	case strings.HasSuffix(name, ".tar"):
+		return ArchiveTar, nil
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveZip, nil
+	}
+	return ArchiveAuto, ErrUnknownArchiveFormat
+}
+
+// archiveWriter hides the differences between tar and zip archives when creating them
+type archiveWriter struct {
+	gzipWriter *gzip.Writer
+	tarWriter  *tar.Writer
+	zipWriter  *zip.Writer
+}
+
+func newArchiveWriter(output io.Writer, format ArchiveFormat) *archiveWriter {
+	if format == ArchiveZip {
+		return &archiveWriter{zipWriter: zip.NewWriter(output)}
+	}
+	writer := &archiveWriter{}
+	if format == ArchiveTarGz {
+		writer.gzipWriter = gzip.NewWriter(output)
+		output = writer.gzipWriter
+	}
+	writer.tarWriter = tar.NewWriter(output)
+	return writer
+}
+
+func (w *archiveWriter) addDir(relPath string, info os.FileInfo) error {
+	if w.zipWriter != nil {
+		_, err := w.zipWriter.CreateHeader(w.zipHeader(relPath+"/", info, zip.Store))
+		return err
+	}
+	return w.writeTarHeader(relPath+"/", "", info)
+}
+
+func (w *archiveWriter) addSymlink(relPath, linkTarget string, info os.FileInfo) error {
+	if w.zipWriter != nil {
+		// zip stores link targets as the entry's content
+		entryWriter, err := w.zipWriter.CreateHeader(w.zipHeader(relPath, info, zip.Store))
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(entryWriter, linkTarget)
+		return err
+	}
+	return w.writeTarHeader(relPath, linkTarget, info)
+}
+
+func (w *archiveWriter) addFile(relPath, filePath string, info os.FileInfo) error {
+	fileRef, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer fileRef.Close()
+
+	var entryWriter io.Writer = w.tarWriter
+	if w.zipWriter != nil {
+		entryWriter, err = w.zipWriter.CreateHeader(w.zipHeader(relPath, info, zip.Deflate))
+	} else {
+		err = w.writeTarHeader(relPath, "", info)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entryWriter, fileRef)
+	return err
+}
+
+// tarHeader builds a header without owner names and ids, so that archives don't depend on the user creating them
+func (w *archiveWriter) tarHeader(name, linkTarget string, info os.FileInfo) (*tar.Header, error) {
+	header, err := tar.FileInfoHeader(info, linkTarget)
+	if err != nil {
+		return nil, err
+	}
+	header.Name = name
+	header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+	header.Format = tar.FormatPAX
+	return header, nil
+}
+
+func (w *archiveWriter) writeTarHeader(name, linkTarget string, info os.FileInfo) error {
+	header, err := w.tarHeader(name, linkTarget, info)
+	if err != nil {
+		return err
+	}
+	return w.tarWriter.WriteHeader(header)
+}
+
+func (w *archiveWriter) zipHeader(name string, info os.FileInfo, method uint16) *zip.FileHeader {
+	header := &zip.FileHeader{Name: name, Method: method, Modified: info.ModTime()}
+	header.SetMode(info.Mode())
+	return header
+}
+
+func (w *archiveWriter) close() error {
+	if w.zipWriter != nil {
+		return w.zipWriter.Close()
+	}
+	if err := w.tarWriter.Close(); err != nil {
+		return err
+	}
+	if w.gzipWriter != nil {
+		return w.gzipWriter.Close()
+	}
+	return nil
+}
+
+// archiveExtractor writes archive entries below dstDirPath. All file system access goes through an os.Root, so that
+// even links already on disk can't redirect writes outside of it. Dir permissions and times are restored at the end,
+// as read-only dirs would prevent extracting their content and every extracted file changes its dir's modification
+// time.
+type archiveExtractor struct {
+	dstDirPath string
+	root       *os.Root
+	filter     *Filter
+	dirs       []extractedDir
+}
+
+type extractedDir struct {
+	path    string
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (e *archiveExtractor) extractTar(archivePath string, gzipped bool) error {
+	archiveFileRef, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archiveFileRef.Close()
+
+	var input io.Reader = archiveFileRef
+	if gzipped {
+		gzipReader, err := gzip.NewReader(archiveFileRef)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		input = gzipReader
+	}
+
+	tarReader := tar.NewReader(input)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		mode := header.FileInfo().Mode()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = e.extractDir(header.Name, mode, header.ModTime)
+		case tar.TypeReg:
+			err = e.extractFile(header.Name, mode, header.ModTime, tarReader)
+		case tar.TypeSymlink:
+			err = e.extractSymlink(header.Name, header.Linkname)
+		case tar.TypeLink:
+			err = e.extractHardLink(header.Name, header.Linkname)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (e *archiveExtractor) extractZip(archivePath string) error {
+	zipReader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer zipReader.Close()
+
+	for _, entry := range zipReader.File {
+		if err = e.extractZipEntry(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *archiveExtractor) extractZipEntry(entry *zip.File) error {
+	mode := entry.Mode()
+	if mode.IsDir() {
+		return e.extractDir(entry.Name, mode, entry.Modified)
+	}
+	if mode&os.ModeSymlink == 0 && !mode.IsRegular() {
+		return nil
+	}
+	entryReader, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer entryReader.Close()
+	if mode&os.ModeSymlink != 0 {
+		linkTarget, err := io.ReadAll(entryReader)
+		if err != nil {
+			return err
+		}
+		return e.extractSymlink(entry.Name, string(linkTarget))
+	}
+	return e.extractFile(entry.Name, mode, entry.Modified, entryReader)
+}
+
+// targetPath maps an entry name to its path relative to dstDirPath, rejecting names which would leave it. Returns ""
+// for entries not selected by the filter.
+func (e *archiveExtractor) targetPath(name string, isDir bool) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	relPath := path.Clean(name)
+	if path.IsAbs(name) || filepath.VolumeName(name) != "" || relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeArchivePath, name)
+	}
+	// like walkFiltered, dirs are only extracted on their own if they match the include patterns
+	if relPath == "." || !e.filter.Match(relPath, isDir) || (isDir && !e.filter.includes(relPath, true)) {
+		return "", nil
+	}
+	return filepath.FromSlash(relPath), nil
+}
+
+func (e *archiveExtractor) extractDir(name string, mode os.FileMode, modTime time.Time) error {
+	dirPath, err := e.targetPath(name, true)
+	if err != nil || dirPath == "" {
+		return err
+	}
+	if err = e.checkNoSymlinks(dirPath); err != nil {
+		return err
+	}
+	if err = e.root.MkdirAll(dirPath, 0755); err != nil {
+		return err
+	}
+	e.dirs = append(e.dirs, extractedDir{path: dirPath, mode: mode, modTime: modTime})
+	return nil
+}
+
+func (e *archiveExtractor) extractFile(name string, mode os.FileMode, modTime time.Time, content io.Reader) error {
+	filePath, err := e.targetPath(name, false)
+	if err != nil || filePath == "" {
+		return err
+	}
+	if err = e.prepareEntry(filePath); err != nil {
+		return err
+	}
+	fileRef, err := e.root.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer fileRef.Close()
+	if _, err = io.Copy(fileRef, content); err != nil {
+		return err
+	}
+	if err = fileRef.Close(); err != nil {
+		return err
+	}
+	// Chmod isn't subject to the umask, unlike the mode passed on creation
+	if err = e.root.Chmod(filePath, mode&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+		return err
+	}
+	return e.root.Chtimes(filePath, time.Time{}, modTime)
+}
+
+// extractSymlink creates the link unless its target would point outside dstDirPath - later entries could be written
+// through it otherwise
+func (e *archiveExtractor) extractSymlink(name, linkTarget string) error {
+	linkPath, err := e.targetPath(name, false)
+	if err != nil || linkPath == "" {
+		return err
+	}
+	if !e.containsLinkTarget(linkPath, linkTarget) {
+		return fmt.Errorf("%w: %s -> %s", ErrUnsafeArchivePath, name, linkTarget)
+	}
+	if err = e.prepareEntry(linkPath); err != nil {
+		return err
+	}
+	return e.root.Symlink(linkTarget, linkPath)
+}
+
+func (e *archiveExtractor) extractHardLink(name, linkTarget string) error {
+	linkPath, err := e.targetPath(name, false)
+	if err != nil || linkPath == "" {
+		return err
+	}
+	targetPath, err := e.targetPath(linkTarget, false)
+	if err != nil {
+		return err
+	}
+	if targetPath == "" {
+		return nil
+	}
+	if err = e.checkNoSymlinks(filepath.Dir(targetPath)); err != nil {
+		return err
+	}
+	if err = e.prepareEntry(linkPath); err != nil {
+		return err
+	}
+	return e.root.Link(targetPath, linkPath)
+}
+
+// prepareEntry creates the parent dirs of a file or link and removes an existing entry at its path. Like tar, existing
+// files are unlinked rather than overwritten, so files hard linked to them keep their content.
+func (e *archiveExtractor) prepareEntry(entryPath string) error {
+	if err := e.checkNoSymlinks(filepath.Dir(entryPath)); err != nil {
+		return err
+	}
+	if err := e.root.MkdirAll(filepath.Dir(entryPath), 0755); err != nil {
+		return err
+	}
+	return e.root.RemoveAll(entryPath)
+}
+
+// checkNoSymlinks refuses to extract through an existing symlink, like bsdtar does. The os.Root would stop any escape
+// anyway, this check reports it as ErrUnsafeArchivePath.
+func (e *archiveExtractor) checkNoSymlinks(relPath string) error {
+	currentPath := ""
+	for _, segment := range strings.Split(relPath, string(filepath.Separator)) {
+		if segment == "." {
+			continue
+		}
+		currentPath = filepath.Join(currentPath, segment)
+		info, err := e.root.Lstat(currentPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %s lies behind the symlink %s", ErrUnsafeArchivePath, relPath, currentPath)
+		}
+	}
+	return nil
+}
+
+// containsLinkTarget tells if a link's target lies within dstDirPath. The target is resolved segment by segment, as
+// ".." after an existing symlink leads to the parent of the symlink's target, not of the symlink itself.
+func (e *archiveExtractor) containsLinkTarget(linkPath, linkTarget string) bool {
+	linkTarget = filepath.FromSlash(linkTarget)
+	var relTarget string
+	if filepath.IsAbs(linkTarget) {
+		var err error
+		if relTarget, err = filepath.Rel(e.dstDirPath, linkTarget); err != nil {
+			return false
+		}
+	} else {
+		// not joined, as that would clean the ".." segments away
+		relTarget = filepath.Dir(linkPath) + string(filepath.Separator) + linkTarget
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(relTarget, string(filepath.Separator)) {
+		switch segment {
+		case "", ".":
+		case "..":
+			if len(segments) == 0 {
+				return false
+			}
+			info, err := e.root.Lstat(filepath.Join(segments...))
+			if err == nil && info.Mode()&os.ModeSymlink != 0 {
+				return false
+			}
+			segments = segments[:len(segments)-1]
+		default:
+			segments = append(segments, segment)
+		}
+	}
+	return true
+}
+
+// restoreDirs applies the dir permissions and times, innermost dirs first
+func (e *archiveExtractor) restoreDirs() error {
+	for i := len(e.dirs) - 1; i >= 0; i-- {
+		dir := e.dirs[i]
+		if err := e.root.Chmod(dir.path, dir.mode&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			return err
+		}
+		if err := e.root.Chtimes(dir.path, time.Time{}, dir.modTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
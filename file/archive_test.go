@@ -0,0 +1,218 @@
+package file_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCreateAndExtractArchive(test *testing.T) {
+	for _, archiveName := range []string{"tree.tar", "tree.tar.gz", "tree.tgz", "tree.zip"} {
+		test.Run(archiveName, func(t *testing.T) {
+			srcDirPath := filepath.Join(t.TempDir(), "src")
+			dstDirPath := filepath.Join(t.TempDir(), "dst")
+			archivePath := filepath.Join(t.TempDir(), archiveName)
+			createTree(t, srcDirPath, map[string]string{
+				"README.md": "readme", "bin/run.sh": "#!/bin/sh", "sub/deep/file.txt": "deep", "build/out.o": "binary",
+			})
+			if err := os.Chmod(filepath.Join(srcDirPath, "bin/run.sh"), 0750); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink("README.md", filepath.Join(srcDirPath, "link.md")); err != nil {
+				t.Skipf("Symlinks not supported: %v", err)
+			}
+
+			options := file.ArchiveOptions{FilterOptions: file.FilterOptions{Exclude: []string{"build/"}}}
+			if err := file.CreateArchive(srcDirPath, archivePath, options); err != nil {
+				t.Fatalf("Unexpected error creating: %v", err)
+			}
+			if err := file.ExtractArchive(archivePath, dstDirPath, file.ArchiveOptions{}); err != nil {
+				t.Fatalf("Unexpected error extracting: %v", err)
+			}
+
+			expected := []string{"README.md", "bin", "bin/run.sh", "link.md", "sub", "sub/deep", "sub/deep/file.txt"}
+			if tree := listTree(t, dstDirPath); !reflect.DeepEqual(tree, expected) {
+				t.Errorf("Expected tree %v, got %v", expected, tree)
+			}
+			if content := file.ReadFile(filepath.Join(dstDirPath, "sub/deep/file.txt")); content != "deep" {
+				t.Errorf("Expected content %q, got %q", "deep", content)
+			}
+			if info, err := os.Stat(filepath.Join(dstDirPath, "bin/run.sh")); err != nil || info.Mode().Perm() != 0750 {
+				t.Errorf("Expected mode 0750, got %v (%v)", info.Mode(), err)
+			}
+			if linkTarget, err := os.Readlink(filepath.Join(dstDirPath, "link.md")); err != nil || linkTarget != "README.md" {
+				t.Errorf("Expected link to README.md, got %q (%v)", linkTarget, err)
+			}
+		})
+	}
+}
+
+func TestExtractArchiveFilter(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	archivePath := filepath.Join(test.TempDir(), "tree.zip")
+	createTree(test, srcDirPath, map[string]string{"a.go": "a", "b.txt": "b", "sub/c.go": "c", "other/d.txt": "d"})
+
+	if err := file.CreateArchive(srcDirPath, archivePath, file.ArchiveOptions{}); err != nil {
+		test.Fatalf("Unexpected error creating: %v", err)
+	}
+	options := file.ArchiveOptions{FilterOptions: file.FilterOptions{Include: []string{"*.go"}}}
+	if err := file.ExtractArchive(archivePath, dstDirPath, options); err != nil {
+		test.Fatalf("Unexpected error extracting: %v", err)
+	}
+
+	expected := []string{"a.go", "sub", "sub/c.go"}
+	if tree := listTree(test, dstDirPath); !reflect.DeepEqual(tree, expected) {
+		test.Errorf("Expected tree %v, got %v", expected, tree)
+	}
+}
+
+func TestCreateArchiveInsideSource(test *testing.T) {
+	srcDirPath := test.TempDir()
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	createTree(test, srcDirPath, map[string]string{"a.txt": "a"})
+	archivePath := filepath.Join(srcDirPath, "self.tar.gz")
+
+	if err := file.CreateArchive(srcDirPath, archivePath, file.ArchiveOptions{}); err != nil {
+		test.Fatalf("Unexpected error creating: %v", err)
+	}
+	if err := file.ExtractArchive(archivePath, dstDirPath, file.ArchiveOptions{}); err != nil {
+		test.Fatalf("Unexpected error extracting: %v", err)
+	}
+
+	if tree := listTree(test, dstDirPath); !reflect.DeepEqual(tree, []string{"a.txt"}) {
+		test.Errorf("Expected the archive to leave itself out, got %v", tree)
+	}
+}
+
+func TestExtractArchiveHardLinkedFile(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	archivePath := filepath.Join(test.TempDir(), "tree.tar.gz")
+	createTree(test, srcDirPath, map[string]string{"a.txt": "new"})
+	createTree(test, dstDirPath, map[string]string{"a.txt": "old"})
+	linkPath := filepath.Join(test.TempDir(), "a.txt")
+	if err := os.Link(filepath.Join(dstDirPath, "a.txt"), linkPath); err != nil {
+		test.Skipf("Hard links not supported: %v", err)
+	}
+
+	if err := file.CreateArchive(srcDirPath, archivePath, file.ArchiveOptions{}); err != nil {
+		test.Fatalf("Unexpected error creating: %v", err)
+	}
+	if err := file.ExtractArchive(archivePath, dstDirPath, file.ArchiveOptions{}); err != nil {
+		test.Fatalf("Unexpected error extracting: %v", err)
+	}
+
+	if content := file.ReadFile(filepath.Join(dstDirPath, "a.txt")); content != "new" {
+		test.Errorf("Expected the extracted content, got %q", content)
+	}
+	if content := file.ReadFile(linkPath); content != "old" {
+		test.Errorf("Expected the hard linked file to be left alone, got %q", content)
+	}
+}
+
+func TestArchiveUnknownFormat(test *testing.T) {
+	err := file.CreateArchive(test.TempDir(), filepath.Join(test.TempDir(), "tree.rar"), file.ArchiveOptions{})
+	if !errors.Is(err, file.ErrUnknownArchiveFormat) {
+		test.Errorf("Expected ErrUnknownArchiveFormat, got %v", err)
+	}
+}
+
+func TestExtractArchiveUnsafePaths(test *testing.T) {
+	testCases := []struct {
+		name    string
+		headers []tar.Header
+	}{
+		{name: "parent dir", headers: []tar.Header{{Typeflag: tar.TypeReg, Name: "../evil.txt", Mode: 0644}}},
+		{name: "nested parent dir", headers: []tar.Header{{Typeflag: tar.TypeReg, Name: "sub/../../evil.txt", Mode: 0644}}},
+		{name: "absolute", headers: []tar.Header{{Typeflag: tar.TypeReg, Name: "/tmp/evil.txt", Mode: 0644}}},
+		{name: "symlink", headers: []tar.Header{{Typeflag: tar.TypeSymlink, Name: "evil", Linkname: "../..", Mode: 0777}}},
+		{name: "absolute symlink", headers: []tar.Header{{Typeflag: tar.TypeSymlink, Name: "evil", Linkname: "/etc", Mode: 0777}}},
+		{name: "hard link", headers: []tar.Header{{Typeflag: tar.TypeLink, Name: "evil", Linkname: "../evil.txt"}}},
+		{
+			name: "chained symlinks",
+			headers: []tar.Header{
+				{Typeflag: tar.TypeDir, Name: "d1/", Mode: 0755},
+				{Typeflag: tar.TypeSymlink, Name: "d1/d2", Linkname: "..", Mode: 0777},
+				{Typeflag: tar.TypeSymlink, Name: "s1", Linkname: "d1/d2/..", Mode: 0777},
+				{Typeflag: tar.TypeReg, Name: "s1/evil.txt", Mode: 0644},
+			},
+		},
+		{
+			name: "write through symlink",
+			headers: []tar.Header{
+				{Typeflag: tar.TypeDir, Name: "d1/", Mode: 0755},
+				{Typeflag: tar.TypeSymlink, Name: "link", Linkname: "d1", Mode: 0777},
+				{Typeflag: tar.TypeReg, Name: "link/evil.txt", Mode: 0644},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			parentDirPath := t.TempDir()
+			dstDirPath := filepath.Join(parentDirPath, "dst")
+			archivePath := filepath.Join(t.TempDir(), "evil.tar")
+			writeTar(t, archivePath, testCase.headers...)
+
+			err := file.ExtractArchive(archivePath, dstDirPath, file.ArchiveOptions{})
+			if !errors.Is(err, file.ErrUnsafeArchivePath) {
+				t.Errorf("Expected ErrUnsafeArchivePath, got %v", err)
+			}
+			if dirEntries, _ := os.ReadDir(parentDirPath); len(dirEntries) != 1 {
+				t.Errorf("Expected nothing to be written outside the destination but found %v", dirEntries)
+			}
+		})
+	}
+}
+
+func TestExtractZipUnsafePath(test *testing.T) {
+	archivePath := filepath.Join(test.TempDir(), "evil.zip")
+	archiveFileRef, err := os.Create(archivePath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	zipWriter := zip.NewWriter(archiveFileRef)
+	entryWriter, err := zipWriter.Create("../evil.txt")
+	if err == nil {
+		_, err = entryWriter.Write([]byte("evil"))
+	}
+	if err == nil {
+		err = zipWriter.Close()
+	}
+	if err == nil {
+		err = archiveFileRef.Close()
+	}
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	err = file.ExtractArchive(archivePath, filepath.Join(test.TempDir(), "dst"), file.ArchiveOptions{})
+	if !errors.Is(err, file.ErrUnsafeArchivePath) {
+		test.Errorf("Expected ErrUnsafeArchivePath, got %v", err)
+	}
+}
+
+// writeTar writes a tar archive with the given content-less entries
+func writeTar(t *testing.T, archivePath string, headers ...tar.Header) {
+	t.Helper()
+	archiveFileRef, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archiveFileRef.Close()
+	tarWriter := tar.NewWriter(archiveFileRef)
+	for _, header := range headers {
+		if err = tarWriter.WriteHeader(&header); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,200 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"github.com/investify-tech/go-utils/must"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// WriteFileAtomic writes a file crash-safe: the content goes into a temp file in the same directory which is synced
+// and then renamed over the destination, so readers either see the old or the new content but never a truncated file
+func WriteFileAtomic(dstFilePath string, fileContent string, executable bool) {
+	must.Void(WriteFileAtomicE(dstFilePath, fileContent, executable))
+}
+
+// WriteFileAtomicE is the error returning variant of WriteFileAtomic. A symlink is written through, not replaced.
+func WriteFileAtomicE(dstFilePath string, fileContent string, executable bool) error {
+	targetPath, err := resolveSymlinks(dstFilePath)
+	if err != nil {
+		return wrapError("write file", dstFilePath, err)
+	}
+	return wrapError("write file", dstFilePath, writeFileAtomic(targetPath, []byte(fileContent), fileModeFor(executable)))
+}
+
+// ReplaceFileContentAtomic replaces value in the file's content by replacement and rewrites the file atomically
+func ReplaceFileContentAtomic(filePath string, value string, replacement string) {
+	must.Void(ReplaceFileContentAtomicE(filePath, value, replacement))
+}
+
+// ReplaceFileContentAtomicE is the error returning variant of ReplaceFileContentAtomic. The content is read through
+// the OSFS, only the write stays OS based since the FS interface has no atomic replace.
+func ReplaceFileContentAtomicE(filePath string, value string, replacement string) error {
+	fsys, name := osFileFS(filePath)
+	input, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return wrapError("replace file content", filePath, err)
+	}
+
+	output := strings.ReplaceAll(string(input), value, replacement)
+
+	return wrapError("replace file content", filePath, writeFileAtomicKeepingMode(filePath, []byte(output)))
+}
+
+// writeFileAtomic does the temp file, fsync, rename and directory fsync dance. The temp file is removed again if
+// anything goes wrong before the rename.
+func writeFileAtomic(filePath string, content []byte, fileMode os.FileMode) error {
+	return writeFileAtomicOwned(filePath, content, fileMode, nil)
+}
+
+// writeFileAtomicOwned is writeFileAtomic giving the file the owner of ownerInfo, if set and supported
+func writeFileAtomicOwned(filePath string, content []byte, fileMode os.FileMode, ownerInfo os.FileInfo) (err error) {
+	dirPath := filepath.Dir(filePath)
+
+	tmpFileRef, err := os.CreateTemp(dirPath, "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpFilePath := tmpFileRef.Name()
+	defer func() {
+		if err != nil {
+			_ = tmpFileRef.Close()
+			_ = os.Remove(tmpFilePath)
+		}
+	}()
+
+	if _, err = tmpFileRef.Write(content); err != nil {
+		return err
+	}
+	if err = chownLike(tmpFileRef, ownerInfo); err != nil {
+		return err
+	}
+	// CreateTemp always uses 0600, so the target rights have to be set explicitly - after chown, which may drop the
+	// setuid and setgid bits
+	if err = tmpFileRef.Chmod(fileMode); err != nil {
+		return err
+	}
+	if err = tmpFileRef.Sync(); err != nil {
+		return err
+	}
+	if err = tmpFileRef.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpFilePath, filePath); err != nil {
+		return err
+	}
+	return syncDir(dirPath)
+}
+
+// chownLike gives an open file the owner of ownerInfo unless it has it already, so that processes which aren't
+// allowed to chown can still rewrite their own files
+func chownLike(fileRef *os.File, ownerInfo os.FileInfo) error {
+	if ownerInfo == nil {
+		return nil
+	}
+	uid, gid, ok := fileOwner(ownerInfo)
+	if !ok {
+		return nil
+	}
+	fileInfo, err := fileRef.Stat()
+	if err != nil {
+		return err
+	}
+	if currentUID, currentGID, _ := fileOwner(fileInfo); currentUID == uid && currentGID == gid {
+		return nil
+	}
+	return fileRef.Chown(uid, gid)
+}
+
+// writeFileAtomicKeepingMode is writeFileAtomic keeping the rights and the owner of an existing file, new files get
+// 0644. Symlinks are resolved first, so that a linked file (like a ~/.bashrc linked into a dotfiles repo) gets the new
+// content while the link stays in place.
+func writeFileAtomicKeepingMode(filePath string, content []byte) error {
+	targetPath, err := resolveSymlinks(filePath)
+	if err != nil {
+		return err
+	}
+	fileInfo, err := os.Stat(targetPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return writeFileAtomic(targetPath, content, fileModeFor(false))
+	} else if err != nil {
+		return err
+	}
+	return writeFileAtomicOwned(targetPath, content, fileInfo.Mode().Perm(), fileInfo)
+}
+
+// resolveSymlinks is filepath.EvalSymlinks also following dangling symlinks to the path where the file would be
+func resolveSymlinks(filePath string) (string, error) {
+	resolvedPath, err := filepath.EvalSymlinks(filePath)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return resolvedPath, err
+	}
+	// the limit of Linux
+	for range 40 {
+		fileInfo, err := os.Lstat(filePath)
+		if errors.Is(err, fs.ErrNotExist) || (err == nil && fileInfo.Mode()&os.ModeSymlink == 0) {
+			return filePath, nil
+		} else if err != nil {
+			return "", err
+		}
+		linkTarget, err := os.Readlink(filePath)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(linkTarget) {
+			linkTarget = filepath.Join(filepath.Dir(filePath), linkTarget)
+		}
+		filePath = linkTarget
+	}
+	return "", ErrSymlinkLoop
+}
+
+// copyFileAtomic atomically replaces dst by a copy of src, including its mode and modification time
+func copyFileAtomic(srcFilePath, dstFilePath string) (err error) {
+	srcFileInfo, err := os.Stat(srcFilePath)
+	if err != nil {
+		return err
+	}
+	dirPath := filepath.Dir(dstFilePath)
+	tmpFileRef, err := os.CreateTemp(dirPath, "."+filepath.Base(dstFilePath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpFilePath := tmpFileRef.Name()
+	_ = tmpFileRef.Close()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmpFilePath)
+		}
+	}()
+
+	if err = copyFile(context.Background(), srcFilePath, tmpFilePath, newStreamReplacer(nil)); err != nil {
+		return err
+	}
+	if err = os.Chtimes(tmpFilePath, time.Time{}, srcFileInfo.ModTime()); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpFilePath, dstFilePath); err != nil {
+		return err
+	}
+	return syncDir(dirPath)
+}
+
+// syncDir flushes a directory so that a rename within it survives a crash. Windows can't open directories for
+// syncing, there the rename itself has to be good enough.
+func syncDir(dirPath string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	dirRef, err := os.Open(dirPath)
+	if err != nil {
+		return err
+	}
+	defer dirRef.Close()
+	return dirRef.Sync()
+}
@@ -0,0 +1,106 @@
+package file_test
+
+import (
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomicE(test *testing.T) {
+	testCases := []struct {
+		name         string
+		existing     string
+		content      string
+		executable   bool
+		expectedMode os.FileMode
+	}{
+		{
+			name:         "new file",
+			content:      "hello",
+			expectedMode: 0644,
+		},
+		{
+			name:         "overwrite existing file",
+			existing:     "old content which is longer than the new one",
+			content:      "new",
+			expectedMode: 0644,
+		},
+		{
+			name:         "executable file",
+			content:      "#!/bin/sh",
+			executable:   true,
+			expectedMode: 0755,
+		},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			dirPath := t.TempDir()
+			filePath := filepath.Join(dirPath, "config.yaml")
+			if testCase.existing != "" {
+				file.WriteFile(filePath, testCase.existing, false)
+			}
+
+			if err := file.WriteFileAtomicE(filePath, testCase.content, testCase.executable); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result := file.ReadFile(filePath); result != testCase.content {
+				t.Errorf("Expected %q but got %q", testCase.content, result)
+			}
+			fileInfo, _ := os.Stat(filePath)
+			if fileInfo.Mode().Perm() != testCase.expectedMode {
+				t.Errorf("Expected mode %v but got %v", testCase.expectedMode, fileInfo.Mode().Perm())
+			}
+			dirEntries, _ := os.ReadDir(dirPath)
+			if len(dirEntries) != 1 {
+				t.Errorf("Expected no leftover temp files but got %d entries", len(dirEntries))
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomicEMissingDir(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "missing", "config.yaml")
+	if err := file.WriteFileAtomicE(filePath, "content", false); err == nil {
+		test.Errorf("Expected an error for a missing directory")
+	}
+}
+
+func TestWriteFileAtomicESymlink(test *testing.T) {
+	dirPath := test.TempDir()
+	createTree(test, dirPath, map[string]string{"releases/v2/config.yaml": "old"})
+	linkPath := filepath.Join(dirPath, "config.yaml")
+	if err := os.Symlink(filepath.Join("releases", "v2", "config.yaml"), linkPath); err != nil {
+		test.Skipf("Symlinks not supported: %v", err)
+	}
+
+	if err := file.WriteFileAtomicE(linkPath, "new", false); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	if linkInfo, _ := os.Lstat(linkPath); linkInfo.Mode()&os.ModeSymlink == 0 {
+		test.Errorf("Expected the symlink to be kept but got mode %v", linkInfo.Mode())
+	}
+	if result := file.ReadFile(filepath.Join(dirPath, "releases", "v2", "config.yaml")); result != "new" {
+		test.Errorf("Expected the link target to be written but got %q", result)
+	}
+}
+
+func TestReplaceFileContentAtomicE(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "run.sh")
+	file.WriteFile(filePath, "echo NAME NAME", true)
+
+	if err := file.ReplaceFileContentAtomicE(filePath, "NAME", "go-utils"); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result := file.ReadFile(filePath); result != "echo go-utils go-utils" {
+		test.Errorf("Expected replaced content but got %q", result)
+	}
+	fileInfo, _ := os.Stat(filePath)
+	if fileInfo.Mode().Perm() != 0755 {
+		test.Errorf("Expected the original mode to be kept but got %v", fileInfo.Mode().Perm())
+	}
+}
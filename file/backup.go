@@ -0,0 +1,237 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BackupMode selects how backups are named
+type BackupMode int
+
+const (
+	// BackupNumbered names backups "<name>.<n><suffix>", counting up from 1
+	BackupNumbered BackupMode = iota
+	// BackupTimestamped names backups "<name>.<UTC time><suffix>", e.g. "app.conf.20260102-150405.000000000.bak"
+	BackupTimestamped
+)
+
+// backupTimeLayout sorts lexically in time order
+const backupTimeLayout = "20060102-150405.000000000"
+
+// BackupOptions configures how and where backups of files are created. The zero value creates numbered ".bak" files
+// next to the backed up file.
+type BackupOptions struct {
+	Mode BackupMode
+	// Suffix overrides the default ".bak"
+	Suffix string
+	// Dir places the backups in a dir of their own (created if missing) instead of next to the file. Only the file's
+	// name is kept, so files with equal names from different dirs should get different backup dirs.
+	Dir string
+}
+
+func (o BackupOptions) suffix() string {
+	if o.Suffix == "" {
+		return ".bak"
+	}
+	return o.Suffix
+}
+
+func (o BackupOptions) dir(filePath string) string {
+	if o.Dir == "" {
+		return filepath.Dir(filePath)
+	}
+	return o.Dir
+}
+
+// BackupFile copies a file to a new backup, keeping its mode and modification time, and returns the backup's path.
+// A missing file has nothing to back up, which is reported by an empty path without an error.
+func BackupFile(filePath string, options BackupOptions) (string, error) {
+	backupPath, err := backupFile(filePath, options)
+	return backupPath, wrapError("backup file", filePath, err)
+}
+
+// Backups lists the existing backups of a file as created with the given options, oldest first
+func Backups(filePath string, options BackupOptions) ([]string, error) {
+	backups, err := listBackups(filePath, options)
+	if err != nil {
+		return nil, wrapError("list backups", filePath, err)
+	}
+	backupPaths := make([]string, 0, len(backups))
+	for _, backup := range backups {
+		backupPaths = append(backupPaths, backup.path)
+	}
+	return backupPaths, nil
+}
+
+// RestoreBackup atomically replaces a file by the content, mode and modification time of one of its backups. The
+// backup is kept.
+func RestoreBackup(filePath string, backupPath string) error {
+	return wrapError("restore backup", filePath, copyFileAtomic(backupPath, filePath))
+}
+
+// RestoreLatestBackup restores the newest backup of a file and returns its path
+func RestoreLatestBackup(filePath string, options BackupOptions) (string, error) {
+	backups, err := listBackups(filePath, options)
+	if err != nil {
+		return "", wrapError("restore backup", filePath, err)
+	}
+	if len(backups) == 0 {
+		return "", wrapError("restore backup", filePath, fmt.Errorf("no backup found: %w", fs.ErrNotExist))
+	}
+	backupPath := backups[len(backups)-1].path
+	return backupPath, RestoreBackup(filePath, backupPath)
+}
+
+// WriteFileWithBackup is WriteFileE backing up an existing file first. If writing fails, the file is restored from
+// the backup. The backup's path is returned, it's empty if there was no file to back up.
+func WriteFileWithBackup(dstFilePath string, fileContent string, executable bool, options BackupOptions) (string, error) {
+	return withBackup(dstFilePath, options, func() error {
+		return WriteFileE(dstFilePath, fileContent, executable)
+	})
+}
+
+// ReplaceFileContentWithBackup is ReplaceFileContentE backing up the file first, see WriteFileWithBackup
+func ReplaceFileContentWithBackup(filePath string, value string, replacement string, options BackupOptions) (string, error) {
+	return withBackup(filePath, options, func() error {
+		return ReplaceFileContentE(filePath, value, replacement)
+	})
+}
+
+// CopyFileWithBackup is CopyFile backing up an existing dst file first, see WriteFileWithBackup
+func CopyFileWithBackup(srcPath, dstPath string, options BackupOptions) (string, error) {
+	return withBackup(dstPath, options, func() error {
+		return wrapError("copy file", srcPath, CopyFile(srcPath, dstPath))
+	})
+}
+
+// withBackup backs up a file before changing it and restores it if the change fails
+func withBackup(filePath string, options BackupOptions, change func() error) (string, error) {
+	backupPath, err := BackupFile(filePath, options)
+	if err != nil {
+		return "", err
+	}
+	if err = change(); err != nil && backupPath != "" {
+		if restoreErr := RestoreBackup(filePath, backupPath); restoreErr != nil {
+			return backupPath, fmt.Errorf("%w (%w)", err, restoreErr)
+		}
+	}
+	return backupPath, err
+}
+
+func backupFile(filePath string, options BackupOptions) (string, error) {
+	fileInfo, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if !fileInfo.Mode().IsRegular() {
+		return "", errors.New("not a regular file")
+	}
+	if err = os.MkdirAll(options.dir(filePath), 0755); err != nil {
+		return "", err
+	}
+
+	backupPath, err := createBackupFile(filePath, options)
+	if err != nil {
+		return "", err
+	}
+	if err = copyFile(context.Background(), filePath, backupPath, newStreamReplacer(nil)); err == nil {
+		err = os.Chtimes(backupPath, time.Time{}, fileInfo.ModTime())
+	}
+	if err != nil {
+		_ = os.Remove(backupPath)
+		return "", err
+	}
+	return backupPath, nil
+}
+
+// createBackupFile exclusively creates the next free backup file, so that concurrent backups don't overwrite each other
+func createBackupFile(filePath string, options BackupOptions) (string, error) {
+	backupTime := time.Now().UTC()
+	number := 1
+	if options.Mode == BackupNumbered {
+		backups, err := listBackups(filePath, options)
+		if err != nil {
+			return "", err
+		}
+		if len(backups) > 0 {
+			number = backups[len(backups)-1].number + 1
+		}
+	}
+
+	for {
+		var version string
+		switch options.Mode {
+		case BackupNumbered:
+			version = strconv.Itoa(number)
+			number++
+		case BackupTimestamped:
+			version = backupTime.Format(backupTimeLayout)
+			backupTime = backupTime.Add(time.Nanosecond)
+		default:
+			return "", fmt.Errorf("unsupported backup mode %d", options.Mode)
+		}
+		backupPath := filepath.Join(options.dir(filePath), filepath.Base(filePath)+"."+version+options.suffix())
+		backupFileRef, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		return backupPath, backupFileRef.Close()
+	}
+}
+
+type backup struct {
+	path    string
+	version string
+	number  int
+}
+
+// listBackups finds the backups of a file in the mode's naming scheme and sorts them oldest first
+func listBackups(filePath string, options BackupOptions) ([]backup, error) {
+	dirEntries, err := os.ReadDir(options.dir(filePath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(filePath) + "."
+	var backups []backup
+	for _, dirEntry := range dirEntries {
+		version, hasPrefix := strings.CutPrefix(dirEntry.Name(), prefix)
+		version, hasSuffix := strings.CutSuffix(version, options.suffix())
+		if !hasPrefix || !hasSuffix || dirEntry.IsDir() {
+			continue
+		}
+		entry := backup{path: filepath.Join(options.dir(filePath), dirEntry.Name()), version: version}
+		switch options.Mode {
+		case BackupNumbered:
+			if entry.number, err = strconv.Atoi(version); err != nil || entry.number < 1 {
+				continue
+			}
+		case BackupTimestamped:
+			if _, err = time.Parse(backupTimeLayout, version); err != nil {
+				continue
+			}
+		}
+		backups = append(backups, entry)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].number != backups[j].number {
+			return backups[i].number < backups[j].number
+		}
+		return backups[i].version < backups[j].version
+	})
+	return backups, nil
+}
@@ -0,0 +1,144 @@
+package file_test
+
+import (
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestBackupFileModes(test *testing.T) {
+	testCases := []struct {
+		name         string
+		options      file.BackupOptions
+		expectedName *regexp.Regexp
+	}{
+		{name: "numbered", options: file.BackupOptions{}, expectedName: regexp.MustCompile(`^app\.conf\.2\.bak$`)},
+		{
+			name:         "timestamped",
+			options:      file.BackupOptions{Mode: file.BackupTimestamped, Suffix: "~"},
+			expectedName: regexp.MustCompile(`^app\.conf\.\d{8}-\d{6}\.\d{9}~$`),
+		},
+		{name: "backup dir", options: file.BackupOptions{Dir: "backups"}, expectedName: regexp.MustCompile(`^app\.conf\.2\.bak$`)},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			dirPath := t.TempDir()
+			filePath := filepath.Join(dirPath, "app.conf")
+			options := testCase.options
+			if options.Dir != "" {
+				options.Dir = filepath.Join(dirPath, options.Dir)
+			}
+			modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+			var backupPaths []string
+			for _, content := range []string{"v1", "v2"} {
+				file.WriteFile(filePath, content, true)
+				if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+				backupPath, err := file.BackupFile(filePath, options)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				backupPaths = append(backupPaths, backupPath)
+			}
+
+			if !testCase.expectedName.MatchString(filepath.Base(backupPaths[1])) {
+				t.Errorf("Expected a backup name matching %s but got %s", testCase.expectedName, backupPaths[1])
+			}
+			if options.Dir != "" && filepath.Dir(backupPaths[1]) != options.Dir {
+				t.Errorf("Expected the backup in %s but got %s", options.Dir, backupPaths[1])
+			}
+			backupInfo, err := os.Stat(backupPaths[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if file.ReadFile(backupPaths[1]) != "v2" || backupInfo.Mode().Perm() != 0755 || !backupInfo.ModTime().Equal(modTime) {
+				t.Errorf("Expected a copy with content, mode and time but got %q, %v, %v",
+					file.ReadFile(backupPaths[1]), backupInfo.Mode(), backupInfo.ModTime())
+			}
+
+			listed, err := file.Backups(filePath, options)
+			if err != nil || len(listed) != 2 || listed[0] != backupPaths[0] || listed[1] != backupPaths[1] {
+				t.Errorf("Expected backups %v but got %v, error %v", backupPaths, listed, err)
+			}
+
+			file.WriteFile(filePath, "broken", false)
+			restoredPath, err := file.RestoreLatestBackup(filePath, options)
+			if err != nil || restoredPath != backupPaths[1] || file.ReadFile(filePath) != "v2" {
+				t.Errorf("Expected v2 restored from %s but got %q from %s, error %v",
+					backupPaths[1], file.ReadFile(filePath), restoredPath, err)
+			}
+		})
+	}
+}
+
+func TestBackupFileMissing(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "missing.txt")
+
+	backupPath, err := file.BackupFile(filePath, file.BackupOptions{})
+	if err != nil || backupPath != "" {
+		test.Errorf("Expected no backup and no error but got %q, %v", backupPath, err)
+	}
+	if _, err = file.RestoreLatestBackup(filePath, file.BackupOptions{}); !errors.Is(err, fs.ErrNotExist) {
+		test.Errorf("Expected fs.ErrNotExist but got %v", err)
+	}
+}
+
+func TestWithBackupVariants(test *testing.T) {
+	dirPath := test.TempDir()
+	filePath := filepath.Join(dirPath, "file.txt")
+	srcFilePath := filepath.Join(dirPath, "src.txt")
+	file.WriteFile(filePath, "original", false)
+	file.WriteFile(srcFilePath, "copied", false)
+
+	steps := []struct {
+		name     string
+		call     func() (string, error)
+		expected string
+	}{
+		{
+			name: "WriteFileWithBackup",
+			call: func() (string, error) {
+				return file.WriteFileWithBackup(filePath, "written", false, file.BackupOptions{})
+			},
+			expected: "written",
+		},
+		{
+			name: "ReplaceFileContentWithBackup",
+			call: func() (string, error) {
+				return file.ReplaceFileContentWithBackup(filePath, "written", "replaced", file.BackupOptions{})
+			},
+			expected: "replaced",
+		},
+		{
+			name:     "CopyFileWithBackup",
+			call:     func() (string, error) { return file.CopyFileWithBackup(srcFilePath, filePath, file.BackupOptions{}) },
+			expected: "copied",
+		},
+	}
+
+	previous := "original"
+	for _, step := range steps {
+		backupPath, err := step.call()
+		if err != nil {
+			test.Fatalf("%s failed: %v", step.name, err)
+		}
+		if file.ReadFile(filePath) != step.expected || file.ReadFile(backupPath) != previous {
+			test.Errorf("%s: expected %q with backup %q but got %q with backup %q",
+				step.name, step.expected, previous, file.ReadFile(filePath), file.ReadFile(backupPath))
+		}
+		previous = step.expected
+	}
+
+	backupPath, err := file.WriteFileWithBackup(filepath.Join(dirPath, "new.txt"), "new", false, file.BackupOptions{})
+	if err != nil || backupPath != "" {
+		test.Errorf("Expected no backup for a new file but got %q, %v", backupPath, err)
+	}
+}
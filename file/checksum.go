@@ -0,0 +1,240 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrChecksumMismatch is reported when a file's checksum differs from the expected one
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ChecksumAlgorithm selects the hash function of checksums
+type ChecksumAlgorithm int
+
+const (
+	// SHA256 is the algorithm of sha256sum
+	SHA256 ChecksumAlgorithm = iota
+	// SHA512 is the algorithm of sha512sum
+	SHA512
+)
+
+func (a ChecksumAlgorithm) String() string {
+	switch a {
+	case SHA256:
+		return "SHA-256"
+	case SHA512:
+		return "SHA-512"
+	}
+	return fmt.Sprintf("ChecksumAlgorithm(%d)", int(a))
+}
+
+func (a ChecksumAlgorithm) newHash() (hash.Hash, error) {
+	switch a {
+	case SHA256:
+		return sha256.New(), nil
+	case SHA512:
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported checksum algorithm %v", a)
+}
+
+// checksumAlgorithmOf derives the algorithm from the length of a hex encoded checksum
+func checksumAlgorithmOf(checksum string) (ChecksumAlgorithm, bool) {
+	switch len(checksum) {
+	case sha256.Size * 2:
+		return SHA256, true
+	case sha512.Size * 2:
+		return SHA512, true
+	}
+	return 0, false
+}
+
+// ChecksumEntry is a line of a checksum file
+type ChecksumEntry struct {
+	// Checksum is hex encoded in lower case
+	Checksum string
+	// Path is slash separated and relative to the checksum file's dir
+	Path string
+}
+
+// ChecksumOptions configures WriteChecksumFile and VerifyChecksumFile
+type ChecksumOptions struct {
+	// FilterOptions select the files to include in a manifest
+	FilterOptions
+	// Algorithm is used for writing, verifying derives it from each checksum's length
+	Algorithm ChecksumAlgorithm
+	// IgnoreMissing skips entries whose file doesn't exist while verifying, like sha256sum --ignore-missing - this way
+	// a single downloaded artifact can be verified against the checksum file of a whole release
+	IgnoreMissing bool
+}
+
+// FileChecksum returns the hex encoded checksum of a file
+func FileChecksum(filePath string, algorithm ChecksumAlgorithm) (string, error) {
+	checksum, err := fileChecksum(filePath, algorithm)
+	return checksum, wrapError("file checksum", filePath, err)
+}
+
+// VerifyFileChecksum compares the checksum of a file with the expected one (case insensitive), reporting
+// ErrChecksumMismatch if they differ. The algorithm is derived from the expected checksum's length.
+func VerifyFileChecksum(filePath string, expectedChecksum string) error {
+	return wrapError("verify file checksum", filePath, verifyFileChecksum(filePath, expectedChecksum))
+}
+
+// ReadChecksumFile parses a checksum file in the format of sha256sum and sha512sum: lines of "<checksum>  <path>",
+// where a "*" instead of the second space marks binary mode, which makes no difference here
+func ReadChecksumFile(checksumFilePath string) ([]ChecksumEntry, error) {
+	content, err := os.ReadFile(checksumFilePath)
+	if err != nil {
+		return nil, wrapError("read checksum file", checksumFilePath, err)
+	}
+	entries, err := parseChecksums(content)
+	return entries, wrapError("read checksum file", checksumFilePath, err)
+}
+
+// WriteChecksumFile writes a manifest with the checksums of all files below dirPath (sorted by path) to
+// checksumFilePath, which can be checked by VerifyChecksumFile or sha256sum -c from within dirPath. Symlinks and
+// special files are skipped, as is the manifest itself if it's placed in dirPath.
+func WriteChecksumFile(dirPath string, checksumFilePath string, options ChecksumOptions) error {
+	return wrapError("write checksum file", checksumFilePath, writeChecksumFile(dirPath, checksumFilePath, options))
+}
+
+// VerifyChecksumFile checks all files listed in a checksum file, whose paths are relative to the checksum file's dir.
+// All mismatches are reported at once, each wrapping ErrChecksumMismatch and naming the file.
+func VerifyChecksumFile(checksumFilePath string, options ChecksumOptions) error {
+	entries, err := ReadChecksumFile(checksumFilePath)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	verified := 0
+	for _, entry := range entries {
+		filePath := filepath.Join(filepath.Dir(checksumFilePath), filepath.FromSlash(entry.Path))
+		err = verifyFileChecksum(filePath, entry.Checksum)
+		if options.IgnoreMissing && errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, wrapError("verify file checksum", filePath, err))
+			continue
+		}
+		verified++
+	}
+	if verified == 0 && len(errs) == 0 {
+		errs = append(errs, errors.New("no file verified"))
+	}
+	return wrapError("verify checksum file", checksumFilePath, errors.Join(errs...))
+}
+
+func fileChecksum(filePath string, algorithm ChecksumAlgorithm) (string, error) {
+	hash, err := algorithm.newHash()
+	if err != nil {
+		return "", err
+	}
+	fileRef, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer fileRef.Close()
+	if _, err = io.Copy(hash, fileRef); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func verifyFileChecksum(filePath string, expectedChecksum string) error {
+	algorithm, ok := checksumAlgorithmOf(expectedChecksum)
+	if !ok {
+		return fmt.Errorf("invalid checksum '%s'", expectedChecksum)
+	}
+	checksum, err := fileChecksum(filePath, algorithm)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(checksum, expectedChecksum) {
+		return fmt.Errorf("%w: expected %s but got %s", ErrChecksumMismatch, strings.ToLower(expectedChecksum), checksum)
+	}
+	return nil
+}
+
+func writeChecksumFile(dirPath string, checksumFilePath string, options ChecksumOptions) error {
+	filter, err := NewFilter(options.FilterOptions)
+	if err != nil {
+		return err
+	}
+	absChecksumFilePath, err := filepath.Abs(checksumFilePath)
+	if err != nil {
+		return err
+	}
+
+	var content bytes.Buffer
+	err = walkFiltered(dirPath, filter, func(entryPath, relPath string, dirEntry fs.DirEntry) error {
+		if !dirEntry.Type().IsRegular() {
+			return nil
+		}
+		if absEntryPath, err := filepath.Abs(entryPath); err != nil || absEntryPath == absChecksumFilePath {
+			return err
+		}
+		checksum, err := fileChecksum(entryPath, options.Algorithm)
+		if err != nil {
+			return err
+		}
+		content.WriteString(formatChecksumLine(ChecksumEntry{Checksum: checksum, Path: relPath}))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(checksumFilePath, content.Bytes(), 0644)
+}
+
+// checksumEscaper escapes paths like the coreutils do, a line containing escapes is marked by a leading backslash
+var checksumEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+
+var checksumUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r")
+
+func formatChecksumLine(entry ChecksumEntry) string {
+	escapedPath := checksumEscaper.Replace(entry.Path)
+	if escapedPath != entry.Path {
+		return `\` + entry.Checksum + "  " + escapedPath + "\n"
+	}
+	return entry.Checksum + "  " + entry.Path + "\n"
+}
+
+func parseChecksums(content []byte) ([]ChecksumEntry, error) {
+	var entries []ChecksumEntry
+	lineNumber := 0
+	for line, err := range Lines(bytes.NewReader(content), LineOptions{}) {
+		if err != nil {
+			return nil, err
+		}
+		lineNumber++
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		escaped := strings.HasPrefix(line, `\`)
+		line = strings.TrimPrefix(line, `\`)
+		checksum, entryPath, ok := strings.Cut(line, " ")
+		if _, valid := checksumAlgorithmOf(checksum); !ok || !valid || entryPath == "" {
+			return nil, fmt.Errorf("invalid checksum line %d: '%s'", lineNumber, line)
+		}
+		if entryPath[0] == ' ' || entryPath[0] == '*' {
+			entryPath = entryPath[1:]
+		}
+		if escaped {
+			entryPath = checksumUnescaper.Replace(entryPath)
+		}
+		entries = append(entries, ChecksumEntry{Checksum: strings.ToLower(checksum), Path: entryPath})
+	}
+	return entries, nil
+}
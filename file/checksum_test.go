@@ -0,0 +1,159 @@
+package file_test
+
+import (
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// checksums of "hello\n" as printed by sha256sum and sha512sum
+const (
+	helloSHA256 = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	helloSHA512 = "e7c22b994c59d9cf2b48e549b1e24666636045930d3da7c1acb299d1c3b7f931f94aae41edda2c2b207a36e10f8bcb8d45223e54878f5b316e7ce3b6bc019629"
+)
+
+func TestFileChecksum(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "hello.txt")
+	file.WriteFile(filePath, "hello\n", false)
+
+	testCases := []struct {
+		algorithm file.ChecksumAlgorithm
+		expected  string
+	}{
+		{algorithm: file.SHA256, expected: helloSHA256},
+		{algorithm: file.SHA512, expected: helloSHA512},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.algorithm.String(), func(t *testing.T) {
+			checksum, err := file.FileChecksum(filePath, testCase.algorithm)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if checksum != testCase.expected {
+				t.Errorf("Expected %s but got %s", testCase.expected, checksum)
+			}
+			if err = file.VerifyFileChecksum(filePath, strings.ToUpper(testCase.expected)); err != nil {
+				t.Errorf("Expected the checksum to verify but got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyFileChecksumMismatch(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "hello.txt")
+	file.WriteFile(filePath, "hello!\n", false)
+
+	err := file.VerifyFileChecksum(filePath, helloSHA256)
+	var opError *file.OpError
+	if !errors.Is(err, file.ErrChecksumMismatch) || !errors.As(err, &opError) || opError.Path != filePath {
+		test.Errorf("Expected ErrChecksumMismatch for %s but got %v", filePath, err)
+	}
+	if err = file.VerifyFileChecksum(filePath, "1234"); err == nil || errors.Is(err, file.ErrChecksumMismatch) {
+		test.Errorf("Expected an invalid checksum error but got %v", err)
+	}
+}
+
+func TestWriteAndVerifyChecksumFile(test *testing.T) {
+	dirPath := test.TempDir()
+	createTree(test, dirPath, map[string]string{
+		"hello.txt":     "hello\n",
+		"sub/data.bin":  "data",
+		"sub/debug.log": "log",
+	})
+	checksumFilePath := filepath.Join(dirPath, "SHA256SUMS")
+	options := file.ChecksumOptions{FilterOptions: file.FilterOptions{Exclude: []string{"*.log"}}}
+
+	if err := file.WriteChecksumFile(dirPath, checksumFilePath, options); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	lines := file.ReadFileLines(checksumFilePath)
+	if len(lines) != 2 || lines[0] != helloSHA256+"  hello.txt" || !strings.HasSuffix(lines[1], "  sub/data.bin") {
+		test.Errorf("Expected sha256sum formatted lines for hello.txt and sub/data.bin but got %q", lines)
+	}
+	if err := file.VerifyChecksumFile(checksumFilePath, file.ChecksumOptions{}); err != nil {
+		test.Errorf("Expected the checksums to verify but got %v", err)
+	}
+
+	// rewriting must not include the manifest itself
+	if err := file.WriteChecksumFile(dirPath, checksumFilePath, options); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	if lines = file.ReadFileLines(checksumFilePath); len(lines) != 2 {
+		test.Errorf("Expected 2 lines but got %q", lines)
+	}
+
+	file.WriteFile(filepath.Join(dirPath, "hello.txt"), "changed\n", false)
+	if err := os.Remove(filepath.Join(dirPath, "sub", "data.bin")); err != nil {
+		test.Fatal(err)
+	}
+	err := file.VerifyChecksumFile(checksumFilePath, file.ChecksumOptions{})
+	if !errors.Is(err, file.ErrChecksumMismatch) || !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), "hello.txt") {
+		test.Errorf("Expected a mismatch for hello.txt and a missing sub/data.bin but got %v", err)
+	}
+}
+
+func TestVerifyChecksumFileIgnoreMissing(test *testing.T) {
+	dirPath := test.TempDir()
+	file.WriteFile(filepath.Join(dirPath, "hello.txt"), "hello\n", false)
+	checksumFilePath := filepath.Join(dirPath, "SHA512SUMS")
+	file.WriteFile(checksumFilePath, "# release checksums\n"+
+		helloSHA512+" *hello.txt\n"+
+		strings.Repeat("0", 128)+"  other-platform.tar.gz\n", false)
+
+	if err := file.VerifyChecksumFile(checksumFilePath, file.ChecksumOptions{IgnoreMissing: true}); err != nil {
+		test.Errorf("Expected the present file to verify but got %v", err)
+	}
+	if err := file.VerifyChecksumFile(checksumFilePath, file.ChecksumOptions{}); !errors.Is(err, fs.ErrNotExist) {
+		test.Errorf("Expected fs.ErrNotExist but got %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(dirPath, "hello.txt")); err != nil {
+		test.Fatal(err)
+	}
+	if err := file.VerifyChecksumFile(checksumFilePath, file.ChecksumOptions{IgnoreMissing: true}); err == nil {
+		test.Errorf("Expected an error if no file was verified at all")
+	}
+}
+
+func TestReadChecksumFile(test *testing.T) {
+	checksumFilePath := filepath.Join(test.TempDir(), "SHA256SUMS")
+
+	testCases := []struct {
+		name      string
+		content   string
+		expected  []file.ChecksumEntry
+		expectErr bool
+	}{
+		{
+			name:     "text and binary mode",
+			content:  strings.ToUpper(helloSHA256) + "  a.txt\r\n" + helloSHA256 + " *dir/b c.bin\n",
+			expected: []file.ChecksumEntry{{Checksum: helloSHA256, Path: "a.txt"}, {Checksum: helloSHA256, Path: "dir/b c.bin"}},
+		},
+		{
+			name:     "escaped",
+			content:  `\` + helloSHA256 + `  back\\slash\nnewline` + "\n",
+			expected: []file.ChecksumEntry{{Checksum: helloSHA256, Path: "back\\slash\nnewline"}},
+		},
+		{name: "invalid checksum", content: "abc  a.txt\n", expectErr: true},
+		{name: "missing path", content: helloSHA256 + "\n", expectErr: true},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			file.WriteFile(checksumFilePath, testCase.content, false)
+			entries, err := file.ReadChecksumFile(checksumFilePath)
+			if (err != nil) != testCase.expectErr {
+				t.Fatalf("Expected error: %v, got %v", testCase.expectErr, err)
+			}
+			if !reflect.DeepEqual(entries, testCase.expected) {
+				t.Errorf("Expected %v but got %v", testCase.expected, entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CleanOptions configures CleanDirWithOptions
+type CleanOptions struct {
+	// Mode is used for (re)creating the dir, the umask applies as usual. Zero means 0755.
+	Mode os.FileMode
+	// KeepDir only deletes the dir's contents, so that the dir itself keeps its inode, rights and owner
+	KeepDir bool
+	// AllowedBase restricts cleaning to dirs below this dir (not the dir itself)
+	AllowedBase string
+}
+
+// CleanDirWithOptions empties the given dir, creating it if it doesn't exist. To protect against wrong paths (like an
+// empty variable ending up as "/"), it refuses to clean the filesystem root, the user's home dir, the working dir and
+// any of their ancestors, and dirs outside of the allowed base, reporting ErrProtectedDir. Symlinks are resolved
+// before these checks.
+func CleanDirWithOptions(dirPath string, options CleanOptions) error {
+	if err := checkCleanable(dirPath, options.AllowedBase); err != nil {
+		return wrapError("clean dir", dirPath, err)
+	}
+	mode := options.Mode
+	if mode == 0 {
+		mode = 0755
+	}
+
+	if !options.KeepDir {
+		if err := os.RemoveAll(dirPath); err != nil {
+			return wrapError("clean dir", dirPath, err)
+		}
+		return wrapError("clean dir", dirPath, os.MkdirAll(dirPath, mode))
+	}
+	dirEntries, err := os.ReadDir(dirPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return wrapError("clean dir", dirPath, os.MkdirAll(dirPath, mode))
+	}
+	if err != nil {
+		return wrapError("clean dir", dirPath, err)
+	}
+	for _, dirEntry := range dirEntries {
+		entryPath := filepath.Join(dirPath, dirEntry.Name())
+		if err = os.RemoveAll(entryPath); err != nil {
+			return wrapError("clean dir", entryPath, err)
+		}
+	}
+	return nil
+}
+
+// checkCleanable implements the guard rails of CleanDirWithOptions
+func checkCleanable(dirPath, allowedBase string) error {
+	if strings.TrimSpace(dirPath) == "" {
+		return fmt.Errorf("%w: empty path", ErrProtectedDir)
+	}
+	resolvedPath, err := resolvePath(dirPath)
+	if err != nil {
+		return err
+	}
+	if filepath.Dir(resolvedPath) == resolvedPath {
+		return fmt.Errorf("%w: filesystem root", ErrProtectedDir)
+	}
+	if homeDirPath, err := os.UserHomeDir(); err == nil && containsResolvedPath(resolvedPath, homeDirPath) {
+		return fmt.Errorf("%w: home dir or one of its ancestors", ErrProtectedDir)
+	}
+	if workingDirPath, err := os.Getwd(); err == nil && containsResolvedPath(resolvedPath, workingDirPath) {
+		return fmt.Errorf("%w: working dir or one of its ancestors", ErrProtectedDir)
+	}
+	if allowedBase != "" {
+		resolvedBase, err := resolvePath(allowedBase)
+		if err != nil {
+			return err
+		}
+		if resolvedPath == resolvedBase || !containsPath(resolvedBase, resolvedPath) {
+			return fmt.Errorf("%w: not below %s", ErrProtectedDir, allowedBase)
+		}
+	}
+	return nil
+}
+
+// resolvePath makes the path absolute and resolves symlinks in it. For paths which don't exist yet, the longest
+// existing parent is resolved.
+func resolvePath(filePath string) (string, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+	var missingParts []string
+	for {
+		resolvedPath, err := filepath.EvalSymlinks(absPath)
+		if err == nil {
+			return filepath.Join(append([]string{resolvedPath}, missingParts...)...), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) || filepath.Dir(absPath) == absPath {
+			return "", err
+		}
+		missingParts = append([]string{filepath.Base(absPath)}, missingParts...)
+		absPath = filepath.Dir(absPath)
+	}
+}
+
+// containsResolvedPath is containsPath for an unresolved otherPath
+func containsResolvedPath(dirPath, otherPath string) bool {
+	resolvedPath, err := resolvePath(otherPath)
+	return err == nil && containsPath(dirPath, resolvedPath)
+}
+
+// containsPath tells if otherPath is dirPath itself or lies below it, both have to be clean absolute paths
+func containsPath(dirPath, otherPath string) bool {
+	relPath, err := filepath.Rel(dirPath, otherPath)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
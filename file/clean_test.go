@@ -0,0 +1,84 @@
+package file_test
+
+import (
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanDirWithOptionsGuardRails(test *testing.T) {
+	homeDirPath, err := os.UserHomeDir()
+	if err != nil {
+		test.Skipf("No home dir: %v", err)
+	}
+	workingDirPath, err := os.Getwd()
+	if err != nil {
+		test.Fatal(err)
+	}
+	baseDirPath := test.TempDir()
+	linkPath := filepath.Join(test.TempDir(), "root-link")
+	if err = os.Symlink("/", linkPath); err != nil {
+		test.Skipf("Symlinks not supported: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		dirPath     string
+		allowedBase string
+	}{
+		{name: "empty", dirPath: ""},
+		{name: "root", dirPath: "/"},
+		{name: "link to root", dirPath: linkPath},
+		{name: "home", dirPath: homeDirPath},
+		{name: "home parent", dirPath: filepath.Dir(homeDirPath)},
+		{name: "working dir", dirPath: "."},
+		{name: "working dir parent", dirPath: filepath.Dir(workingDirPath)},
+		{name: "outside base", dirPath: test.TempDir(), allowedBase: baseDirPath},
+		{name: "base itself", dirPath: baseDirPath, allowedBase: baseDirPath},
+		{name: "escaping base", dirPath: filepath.Join(baseDirPath, "..", "other"), allowedBase: baseDirPath},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			err := file.CleanDirWithOptions(testCase.dirPath, file.CleanOptions{AllowedBase: testCase.allowedBase})
+			if !errors.Is(err, file.ErrProtectedDir) {
+				t.Errorf("Expected ErrProtectedDir, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCleanDirWithOptions(test *testing.T) {
+	baseDirPath := test.TempDir()
+	dirPath := filepath.Join(baseDirPath, "build")
+	createTree(test, dirPath, map[string]string{"a.txt": "a", "sub/b.txt": "b"})
+	if err := os.Chmod(dirPath, 0750); err != nil {
+		test.Fatal(err)
+	}
+	dirInfo, err := os.Stat(dirPath)
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	err = file.CleanDirWithOptions(dirPath, file.CleanOptions{KeepDir: true, AllowedBase: baseDirPath})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	if tree := listTree(test, dirPath); len(tree) != 0 {
+		test.Errorf("Expected an empty dir, got %v", tree)
+	}
+	newDirInfo, err := os.Stat(dirPath)
+	if err != nil || !os.SameFile(dirInfo, newDirInfo) || newDirInfo.Mode().Perm() != 0750 {
+		test.Errorf("Expected the dir to be kept with mode 0750, got %v (%v)", newDirInfo.Mode(), err)
+	}
+
+	if err = file.CleanDirWithOptions(dirPath, file.CleanOptions{Mode: 0700}); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	if newDirInfo, err = os.Stat(dirPath); err != nil || newDirInfo.Mode().Perm() != 0700 {
+		test.Errorf("Expected the dir to be recreated with mode 0700, got %v (%v)", newDirInfo.Mode(), err)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package file_test
+
+import (
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestCleanDirRespectsUmask(test *testing.T) {
+	oldUmask := syscall.Umask(0027)
+	defer syscall.Umask(oldUmask)
+	dirPath := filepath.Join(test.TempDir(), "build")
+
+	if err := file.CleanDirE(dirPath); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	if dirInfo, err := os.Stat(dirPath); err != nil || dirInfo.Mode().Perm() != 0750 {
+		test.Errorf("Expected mode 0750, got %v (%v)", dirInfo.Mode(), err)
+	}
+}
@@ -0,0 +1,12 @@
+package file
+
+import (
+	"golang.org/x/sys/unix"
+	"os"
+)
+
+// cloneFile makes dst share the data blocks of src (reflink) with the FICLONE ioctl, which copy-on-write file systems
+// like Btrfs or XFS support. Everywhere else an error is returned and the content has to be copied.
+func cloneFile(dst, src *os.File) error {
+	return unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+}
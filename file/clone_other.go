@@ -0,0 +1,13 @@
+//go:build !linux
+
+package file
+
+import (
+	"errors"
+	"os"
+)
+
+// cloneFile is only implemented for Linux so far
+func cloneFile(dst, src *os.File) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,343 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type configFormat int
+
+const (
+	configJSON configFormat = iota
+	configYAML
+	configTOML
+	configEnv
+)
+
+// configFormatOf derives the format from the file name: ".json", ".yaml", ".yml" and ".toml" by extension, dotenv
+// files by an ".env" extension or a name starting with ".env" (like ".env.local")
+func configFormatOf(filePath string) (configFormat, error) {
+	name := strings.ToLower(filepath.Base(filePath))
+	switch filepath.Ext(name) {
+	case ".json":
+		return configJSON, nil
+	case ".yaml", ".yml":
+		return configYAML, nil
+	case ".toml":
+		return configTOML, nil
+	case ".env":
+		return configEnv, nil
+	}
+	if strings.HasPrefix(name, ".env") {
+		return configEnv, nil
+	}
+	return 0, ErrUnknownConfigFormat
+}
+
+// ReadJSON reads a JSON file into a value of type T
+func ReadJSON[T any](filePath string) (T, error) {
+	var value T
+	content, err := os.ReadFile(filePath)
+	if err == nil {
+		err = json.Unmarshal(content, &value)
+	}
+	return value, wrapError("read json", filePath, err)
+}
+
+// WriteJSON writes the value as JSON indented by two spaces, atomically like WriteFileAtomic. The rights of an existing
+// file are kept.
+func WriteJSON(filePath string, value any) error {
+	content, err := marshalJSON(value)
+	if err == nil {
+		err = writeFileAtomicKeepingMode(filePath, content)
+	}
+	return wrapError("write json", filePath, err)
+}
+
+// ReadYAML reads a YAML file into a value of type T
+func ReadYAML[T any](filePath string) (T, error) {
+	var value T
+	content, err := os.ReadFile(filePath)
+	if err == nil {
+		err = yaml.Unmarshal(content, &value)
+	}
+	return value, wrapError("read yaml", filePath, err)
+}
+
+// WriteYAML writes the value as YAML indented by two spaces, atomically like WriteFileAtomic. The rights of an existing
+// file are kept.
+func WriteYAML(filePath string, value any) error {
+	content, err := marshalYAML(value)
+	if err == nil {
+		err = writeFileAtomicKeepingMode(filePath, content)
+	}
+	return wrapError("write yaml", filePath, err)
+}
+
+// ReadTOML reads a TOML file into a value of type T
+func ReadTOML[T any](filePath string) (T, error) {
+	var value T
+	content, err := os.ReadFile(filePath)
+	if err == nil {
+		err = toml.Unmarshal(content, &value)
+	}
+	return value, wrapError("read toml", filePath, err)
+}
+
+// WriteTOML writes the value as TOML indented by two spaces, atomically like WriteFileAtomic. The rights of an existing
+// file are kept. The value has to encode to a table, like a struct or a map with string keys.
+func WriteTOML(filePath string, value any) error {
+	content, err := marshalTOML(value)
+	if err == nil {
+		err = writeFileAtomicKeepingMode(filePath, content)
+	}
+	return wrapError("write toml", filePath, err)
+}
+
+// ReadConfig reads a JSON, YAML, TOML or dotenv file into a value of type T, choosing the format by the file name. Dotenv
+// files are read like a JSON object of strings, so T may be a map or a struct with string fields.
+func ReadConfig[T any](filePath string) (T, error) {
+	var value T
+	format, err := configFormatOf(filePath)
+	if err != nil {
+		return value, wrapError("read config", filePath, err)
+	}
+	switch format {
+	case configJSON:
+		return ReadJSON[T](filePath)
+	case configYAML:
+		return ReadYAML[T](filePath)
+	case configTOML:
+		return ReadTOML[T](filePath)
+	}
+	values, err := ReadEnv(filePath)
+	if err != nil {
+		return value, err
+	}
+	content, err := json.Marshal(values)
+	if err == nil {
+		err = json.Unmarshal(content, &value)
+	}
+	return value, wrapError("read config", filePath, err)
+}
+
+// WriteConfig writes the value as JSON, YAML, TOML or dotenv file, choosing the format by the file name. Dotenv files can
+// only be written from a map[string]string.
+func WriteConfig(filePath string, value any) error {
+	format, err := configFormatOf(filePath)
+	if err != nil {
+		return wrapError("write config", filePath, err)
+	}
+	switch format {
+	case configJSON:
+		return WriteJSON(filePath, value)
+	case configYAML:
+		return WriteYAML(filePath, value)
+	case configTOML:
+		return WriteTOML(filePath, value)
+	}
+	values, ok := value.(map[string]string)
+	if !ok {
+		return wrapError("write config", filePath, fmt.Errorf("dotenv files need a map[string]string, got %T", value))
+	}
+	return WriteEnv(filePath, values)
+}
+
+// SetConfigValue updates a single key of a JSON, YAML, TOML or dotenv file, creating the file if it doesn't exist. For
+// JSON, YAML and TOML, nested keys are separated by dots and missing parent objects are created. As much of the
+// existing file as the format allows is preserved: YAML keeps its key order and comments, dotenv files are only changed
+// in the line of the key (or get it appended), JSON and TOML are rewritten with sorted keys.
+func SetConfigValue(filePath string, key string, value any) error {
+	format, err := configFormatOf(filePath)
+	if err != nil {
+		return wrapError("set config value", filePath, err)
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return wrapError("set config value", filePath, err)
+	}
+	switch format {
+	case configJSON:
+		content, err = setJSONValue(content, key, value)
+	case configYAML:
+		content, err = setYAMLValue(content, key, value)
+	case configTOML:
+		content, err = setTOMLValue(content, key, value)
+	default:
+		content, err = setEnvValue(content, key, fmt.Sprint(value))
+	}
+	if err == nil {
+		err = writeFileAtomicKeepingMode(filePath, content)
+	}
+	return wrapError("set config value", filePath, err)
+}
+
+func marshalJSON(value any) ([]byte, error) {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+func marshalYAML(value any) ([]byte, error) {
+	var output bytes.Buffer
+	encoder := yaml.NewEncoder(&output)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
+
+func marshalTOML(value any) ([]byte, error) {
+	var output bytes.Buffer
+	encoder := toml.NewEncoder(&output)
+	encoder.Indent = "  "
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
+
+func setJSONValue(content []byte, key string, value any) ([]byte, error) {
+	root := map[string]any{}
+	if len(bytes.TrimSpace(content)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		// Keeps numbers as they are instead of converting them to float64
+		decoder.UseNumber()
+		if err := decoder.Decode(&root); err != nil {
+			return nil, err
+		}
+	}
+	if err := setNestedValue(root, key, value); err != nil {
+		return nil, err
+	}
+	return marshalJSON(root)
+}
+
+func setTOMLValue(content []byte, key string, value any) ([]byte, error) {
+	root := map[string]any{}
+	if err := toml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+	if err := setNestedValue(root, key, value); err != nil {
+		return nil, err
+	}
+	return marshalTOML(root)
+}
+
+// setNestedValue sets the value of a dotted key in decoded JSON or TOML, creating missing parent objects
+func setNestedValue(root map[string]any, key string, value any) error {
+	keys := strings.Split(key, ".")
+	object := root
+	for i, parentKey := range keys[:len(keys)-1] {
+		child, exists := object[parentKey]
+		if !exists {
+			child = map[string]any{}
+			object[parentKey] = child
+		}
+		childObject, ok := child.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s is no object", strings.Join(keys[:i+1], "."))
+		}
+		object = childObject
+	}
+	object[keys[len(keys)-1]] = value
+	return nil
+}
+
+func setYAMLValue(content []byte, key string, value any) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	if document.Kind == 0 {
+		// yaml.v3 drops the comments of a document without content, they are kept as its head comment
+		document = yaml.Node{Kind: yaml.DocumentNode, HeadComment: yamlCommentLines(content)}
+	}
+	if len(document.Content) == 0 || document.Content[0].Tag == "!!null" {
+		// An empty document (like "---" alone) is treated as an empty mapping, its comments are kept above it
+		var comments []string
+		if len(document.Content) > 0 {
+			valueNode := document.Content[0]
+			comments = append(comments, valueNode.HeadComment, valueNode.LineComment, valueNode.FootComment)
+		}
+		comments = append([]string{document.HeadComment}, append(comments, document.FootComment)...)
+		document.HeadComment = joinNonEmpty(comments, "\n\n")
+		document.FootComment = ""
+		document.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	var valueNode yaml.Node
+	if err := valueNode.Encode(value); err != nil {
+		return nil, err
+	}
+
+	keys := strings.Split(key, ".")
+	mapping := document.Content[0]
+	for i, mappingKey := range keys {
+		if mapping.Kind != yaml.MappingNode && i == 0 {
+			return nil, errors.New("document is no mapping")
+		} else if mapping.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s is no mapping", strings.Join(keys[:i], "."))
+		}
+		childNode := yamlMappingValue(mapping, mappingKey)
+		if childNode == nil {
+			childNode = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: mappingKey}
+			mapping.Content = append(mapping.Content, keyNode, childNode)
+		}
+		if i == len(keys)-1 {
+			// The comments belong to the entry, not to its value
+			valueNode.HeadComment, valueNode.LineComment, valueNode.FootComment =
+				childNode.HeadComment, childNode.LineComment, childNode.FootComment
+			*childNode = valueNode
+		}
+		mapping = childNode
+	}
+	return marshalYAML(&document)
+}
+
+// yamlMappingValue returns the value node of the key in a mapping node, or nil if the key doesn't exist
+func yamlMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlCommentLines returns the comment lines of a document, keeping the blank lines between them
+func yamlCommentLines(content []byte) string {
+	var lines []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") || (line == "" && len(lines) > 0) {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// joinNonEmpty joins the non-empty strings with the separator
+func joinNonEmpty(values []string, separator string) string {
+	var nonEmpty []string
+	for _, value := range values {
+		if value != "" {
+			nonEmpty = append(nonEmpty, value)
+		}
+	}
+	return strings.Join(nonEmpty, separator)
+}
@@ -0,0 +1,247 @@
+package file_test
+
+import (
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testConfig struct {
+	Name  string `json:"name" yaml:"name" toml:"name"`
+	Port  int    `json:"port" yaml:"port" toml:"port"`
+	Debug bool   `json:"debug" yaml:"debug" toml:"debug"`
+}
+
+func TestWriteAndReadConfig(test *testing.T) {
+	expected := testConfig{Name: "service", Port: 8080, Debug: true}
+
+	for _, fileName := range []string{"config.json", "config.yaml", "config.yml", "config.toml"} {
+		test.Run(fileName, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), fileName)
+
+			if err := file.WriteConfig(filePath, expected); err != nil {
+				t.Fatalf("Unexpected error writing: %v", err)
+			}
+			result, err := file.ReadConfig[testConfig](filePath)
+			if err != nil {
+				t.Fatalf("Unexpected error reading: %v", err)
+			}
+
+			if result != expected {
+				t.Errorf("Expected %+v, got %+v", expected, result)
+			}
+		})
+	}
+}
+
+func TestReadJSONAndYAML(test *testing.T) {
+	dirPath := test.TempDir()
+	jsonPath := filepath.Join(dirPath, "config.json")
+	yamlPath := filepath.Join(dirPath, "config.yaml")
+	file.WriteFile(jsonPath, `{"name": "json", "port": 1}`, false)
+	file.WriteFile(yamlPath, "name: yaml\nport: 2\n", false)
+
+	jsonConfig, err := file.ReadJSON[testConfig](jsonPath)
+	if err != nil || jsonConfig != (testConfig{Name: "json", Port: 1}) {
+		test.Errorf("Unexpected JSON result %+v, error %v", jsonConfig, err)
+	}
+	yamlConfig, err := file.ReadYAML[map[string]any](yamlPath)
+	if expected := map[string]any{"name": "yaml", "port": 2}; err != nil || !reflect.DeepEqual(yamlConfig, expected) {
+		test.Errorf("Unexpected YAML result %v, error %v", yamlConfig, err)
+	}
+
+	file.WriteFile(jsonPath, `{"name": `, false)
+	var opErr *file.OpError
+	if _, err = file.ReadJSON[testConfig](jsonPath); !errors.As(err, &opErr) || opErr.Op != "read json" {
+		test.Errorf("Expected OpError for invalid JSON, got %v", err)
+	}
+}
+
+func TestWriteJSONKeepsMode(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "secret.json")
+	file.WriteFile(filePath, "{}", false)
+	if err := os.Chmod(filePath, 0600); err != nil {
+		test.Fatal(err)
+	}
+
+	if err := file.WriteJSON(filePath, map[string]string{"token": "secret"}); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	if content := file.ReadFile(filePath); content != "{\n  \"token\": \"secret\"\n}\n" {
+		test.Errorf("Unexpected content %q", content)
+	}
+	if info, err := os.Stat(filePath); err != nil || info.Mode().Perm() != 0600 {
+		test.Errorf("Expected mode 0600 to be kept, got %v (%v)", info.Mode(), err)
+	}
+}
+
+func TestSetConfigValue(test *testing.T) {
+	testCases := []struct {
+		name     string
+		fileName string
+		content  string
+		key      string
+		value    any
+		expected string
+	}{
+		{
+			name:     "json nested",
+			fileName: "config.json",
+			content:  `{"server": {"port": 80, "host": "localhost"}, "big": 12345678901234567890}`,
+			key:      "server.port",
+			value:    8080,
+			expected: "{\n  \"big\": 12345678901234567890,\n  \"server\": {\n    \"host\": \"localhost\",\n    \"port\": 8080\n  }\n}\n",
+		},
+		{
+			name:     "json new file",
+			fileName: "config.json",
+			key:      "a.b",
+			value:    true,
+			expected: "{\n  \"a\": {\n    \"b\": true\n  }\n}\n",
+		},
+		{
+			name:     "yaml keeps comments and order",
+			fileName: "config.yaml",
+			content:  "# service config\nname: service\nserver:\n  # the port\n  port: 80 # default\n  host: localhost\n",
+			key:      "server.port",
+			value:    8080,
+			expected: "# service config\nname: service\nserver:\n  # the port\n  port: 8080 # default\n  host: localhost\n",
+		},
+		{
+			name:     "yaml new key",
+			fileName: "config.yml",
+			content:  "name: service\n",
+			key:      "server.tls.enabled",
+			value:    true,
+			expected: "name: service\nserver:\n  tls:\n    enabled: true\n",
+		},
+		{
+			name:     "yaml empty document",
+			fileName: "config.yaml",
+			content:  "---\n",
+			key:      "server.port",
+			value:    8080,
+			expected: "server:\n  port: 8080\n",
+		},
+		{
+			name:     "yaml comments only",
+			fileName: "config.yaml",
+			content:  "# managed by ops\n\n# keep this\n",
+			key:      "name",
+			value:    "service",
+			expected: "# managed by ops\n\n# keep this\n\nname: service\n",
+		},
+		{
+			name:     "yaml comment before document marker",
+			fileName: "config.yaml",
+			content:  "# managed by ops\n---\n",
+			key:      "name",
+			value:    "service",
+			expected: "# managed by ops\n\nname: service\n",
+		},
+		{
+			name:     "toml nested",
+			fileName: "config.toml",
+			content:  "name = \"service\"\n\n[server]\nport = 80\nhost = \"localhost\"\n",
+			key:      "server.port",
+			value:    8080,
+			expected: "name = \"service\"\n\n[server]\n  host = \"localhost\"\n  port = 8080\n",
+		},
+		{
+			name:     "toml new file",
+			fileName: "config.toml",
+			key:      "a.b",
+			value:    true,
+			expected: "[a]\n  b = true\n",
+		},
+		{
+			name:     "env keeps other lines",
+			fileName: ".env",
+			content:  "# settings\nexport NAME=service\nPORT=80 # default\n\nDEBUG=false\n",
+			key:      "PORT",
+			value:    8080,
+			expected: "# settings\nexport NAME=service\nPORT=8080\n\nDEBUG=false\n",
+		},
+		{
+			name:     "env appends and quotes",
+			fileName: "local.env",
+			content:  "NAME=service",
+			key:      "GREETING",
+			value:    "hello \"world\"",
+			expected: "NAME=service\nGREETING=\"hello \\\"world\\\"\"\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), testCase.fileName)
+			if testCase.content != "" {
+				file.WriteFile(filePath, testCase.content, false)
+			}
+
+			if err := file.SetConfigValue(filePath, testCase.key, testCase.value); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if content := file.ReadFile(filePath); content != testCase.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", testCase.expected, content)
+			}
+		})
+	}
+}
+
+func TestConfigUnknownFormat(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "config.ini")
+	if _, err := file.ReadConfig[testConfig](filePath); !errors.Is(err, file.ErrUnknownConfigFormat) {
+		test.Errorf("Expected ErrUnknownConfigFormat, got %v", err)
+	}
+	if err := file.SetConfigValue(filePath, "key", "value"); !errors.Is(err, file.ErrUnknownConfigFormat) {
+		test.Errorf("Expected ErrUnknownConfigFormat, got %v", err)
+	}
+}
+
+func TestReadTOML(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "config.toml")
+	file.WriteFile(filePath, "# service\nname = \"toml\"\nport = 3\n\n[tls]\nenabled = true\n", false)
+
+	config, err := file.ReadTOML[testConfig](filePath)
+	if err != nil || config != (testConfig{Name: "toml", Port: 3}) {
+		test.Errorf("Unexpected TOML result %+v, error %v", config, err)
+	}
+	values, err := file.ReadTOML[map[string]any](filePath)
+	if expected := map[string]any{"name": "toml", "port": int64(3), "tls": map[string]any{"enabled": true}}; err != nil ||
+		!reflect.DeepEqual(values, expected) {
+		test.Errorf("Unexpected TOML result %v, error %v", values, err)
+	}
+
+	file.WriteFile(filePath, "name = ", false)
+	var opErr *file.OpError
+	if _, err = file.ReadTOML[testConfig](filePath); !errors.As(err, &opErr) || opErr.Op != "read toml" {
+		test.Errorf("Expected OpError for invalid TOML, got %v", err)
+	}
+}
+
+func TestSetConfigValueNoMapping(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "config.yaml")
+
+	testCases := []struct {
+		content  string
+		expected string
+	}{
+		{content: "just a string\n", expected: "document is no mapping"},
+		{content: "name: service\n", expected: "name is no mapping"},
+	}
+
+	for _, testCase := range testCases {
+		file.WriteFile(filePath, testCase.content, false)
+		err := file.SetConfigValue(filePath, "name.first", "value")
+		if err == nil || !strings.HasSuffix(err.Error(), ": "+testCase.expected) {
+			test.Errorf("Expected an error ending with %q but got %v", testCase.expected, err)
+		}
+	}
+}
@@ -0,0 +1,637 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SymlinkMode tells CopyDirWithOptions what to do with symbolic links found in the source tree
+type SymlinkMode int
+
+const (
+	// SymlinksFollow copies whatever the link points to, links pointing back into their own parents are reported as
+	// ErrSymlinkLoop instead of recursing forever
+	SymlinksFollow SymlinkMode = iota
+	// SymlinksPreserve recreates the link itself with the very same target
+	SymlinksPreserve
+	// SymlinksSkip ignores links completely
+	SymlinksSkip
+)
+
+// SpecialFileMode tells CopyDirWithOptions what to do with FIFOs, sockets and device files
+type SpecialFileMode int
+
+const (
+	// SpecialFilesError stops the copy with ErrSpecialFile
+	SpecialFilesError SpecialFileMode = iota
+	// SpecialFilesSkip ignores special files
+	SpecialFilesSkip
+)
+
+// CopyOptions configures CopyDirWithOptions - the zero value copies like CopyDir does
+type CopyOptions struct {
+	// Replacements are done in all files' contents (see CopyFileAndReplaceContent)
+	Replacements map[string]string
+	// ReplaceRules are applied to all files' contents after the Replacements (see ReplaceFileContentWithRules)
+	ReplaceRules []ReplaceRule
+	// Template renders all files through text/template instead, which can't be combined with Replacements or
+	// ReplaceRules
+	Template *TemplateOptions
+	// FilterOptions select the entries to copy, paths are matched relative to the source dir
+	FilterOptions
+	Symlinks     SymlinkMode
+	SpecialFiles SpecialFileMode
+	// PreserveTimes sets the modification time of copied files and dirs to the one of the source
+	PreserveTimes bool
+	// PreserveOwner sets uid and gid of copied entries to the ones of the source, which usually requires root
+	PreserveOwner bool
+	// PreserveXattrs copies extended attributes of files and dirs (Linux only)
+	PreserveXattrs bool
+	// DryRun doesn't touch the destination but only plans what would be done, see CopyDirWithReport
+	DryRun bool
+	// Concurrency is the number of files copied in parallel, 0 and 1 copy one file after another
+	Concurrency int
+	// Progress is called after each copied file - never concurrently, so it doesn't need to synchronise
+	Progress func(CopyProgress)
+}
+
+// CopyProgress tells how far a copy has got. The totals are known upfront, as the source tree is walked before any
+// file is copied.
+type CopyProgress struct {
+	FilesDone  int
+	FilesTotal int
+	BytesDone  int64
+	BytesTotal int64
+	// CurrentPath is the source path of the file which has just been copied
+	CurrentPath string
+}
+
+// CopyDirWithOptions copies a whole directory recursively as configured by the given options
+func CopyDirWithOptions(srcDirPath, dstDirPath string, options CopyOptions) error {
+	_, err := CopyDirWithReport(srcDirPath, dstDirPath, options)
+	return err
+}
+
+// CopyDirWithReport copies a whole directory recursively as configured by the given options and reports the created
+// and overwritten files and dirs. With DryRun set, nothing is copied and the report is the plan of what would be done,
+// including diffs of the content changes done by replacements or templates. If an error occurs, the report lists what
+// was done up to this point.
+func CopyDirWithReport(srcDirPath, dstDirPath string, options CopyOptions) (*Report, error) {
+	return CopyDirContext(context.Background(), srcDirPath, dstDirPath, options)
+}
+
+// CopyDirContext is CopyDirWithReport stopping as soon as possible when the context is done. The source tree is walked
+// first, creating the dirs and links, then the files are copied - in parallel if configured. On Linux, plain copies
+// share the data blocks (reflink) where the file system supports it, or at least copy within the kernel.
+func CopyDirContext(ctx context.Context, srcDirPath, dstDirPath string, options CopyOptions) (*Report, error) {
+	return copyDir(ctx, OSFS(srcDirPath), ".", OSFS(dstDirPath), ".", options, nil)
+}
+
+// copyDir is CopyDirFSContext, skipping existing destination files and links for which unchanged returns true. Paths
+// in the report and passed to unchanged are the ones of fsPath.
+func copyDir(ctx context.Context, srcFS fs.FS, srcDirName string, dstFS FS, dstDirName string, options CopyOptions,
+	unchanged func(srcPath, dstPath string, srcInfo os.FileInfo) bool) (*Report, error) {
+	srcDirPath := fsPath(srcFS, srcDirName)
+	srcDirInfo, err := fs.Stat(srcFS, srcDirName)
+	if err != nil {
+		return &Report{DryRun: options.DryRun}, wrapError("copy", srcDirPath, err)
+	}
+	if !srcDirInfo.IsDir() {
+		return &Report{DryRun: options.DryRun}, wrapError("copy", srcDirPath, fmt.Errorf("not a directory"))
+	}
+	if options.Template != nil && (len(options.Replacements) > 0 || len(options.ReplaceRules) > 0) {
+		err = errors.New("template rendering and replacements can't be combined")
+		return &Report{DryRun: options.DryRun}, wrapError("copy", srcDirPath, err)
+	}
+	if err = checkCopyOptions(srcFS, dstFS, options); err != nil {
+		return &Report{DryRun: options.DryRun}, wrapError("copy", srcDirPath, err)
+	}
+	rules, err := compileReplaceRules(options.ReplaceRules)
+	if err != nil {
+		return &Report{DryRun: options.DryRun}, wrapError("copy", srcDirPath, err)
+	}
+	filter, err := NewFilter(options.FilterOptions)
+	if err != nil {
+		return &Report{DryRun: options.DryRun}, wrapError("copy", srcDirPath, err)
+	}
+
+	copier := dirCopier{
+		ctx:       ctx,
+		srcFS:     srcFS,
+		dstFS:     dstFS,
+		options:   options,
+		filter:    filter,
+		replacer:  newStreamReplacer(options.Replacements),
+		rules:     rules,
+		unchanged: unchanged,
+	}
+	err = copier.copyDir(srcDirName, dstDirName, ".", srcDirInfo, nil, nil)
+	if err == nil {
+		err = copier.copyFiles()
+	}
+	if err == nil && !options.DryRun {
+		err = copier.preserveDirMetadata()
+	}
+	return copier.report(), err
+}
+
+// checkCopyOptions reports the options the file systems can't support, instead of silently ignoring them
+func checkCopyOptions(srcFS fs.FS, dstFS FS, options CopyOptions) error {
+	var unsupported []string
+	if _, ok := dstFS.(SymlinkFS); !ok && options.Symlinks == SymlinksPreserve {
+		unsupported = append(unsupported, "preserving symlinks")
+	}
+	if _, ok := dstFS.(ChtimesFS); !ok && options.PreserveTimes {
+		unsupported = append(unsupported, "preserving times")
+	}
+	if _, ok := dstFS.(LchownFS); !ok && options.PreserveOwner {
+		unsupported = append(unsupported, "preserving owners")
+	}
+	_, srcOS := srcFS.(*osFS)
+	_, dstOS := dstFS.(*osFS)
+	if !(srcOS && dstOS) && options.PreserveXattrs {
+		unsupported = append(unsupported, "preserving xattrs")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%s: %w", strings.Join(unsupported, ", "), errors.ErrUnsupported)
+	}
+	return nil
+}
+
+type dirCopier struct {
+	ctx      context.Context
+	srcFS    fs.FS
+	dstFS    FS
+	options  CopyOptions
+	filter   *Filter
+	replacer *streamReplacer
+	rules    replaceRules
+	// unchanged optionally tells if an existing destination is up-to-date already, so that it can be skipped
+	unchanged func(srcPath, dstPath string, srcInfo os.FileInfo) bool
+	// items are all dirs, links and files in the order they have been walked
+	items []*copyItem
+	// copiedDirs are the dirs whose metadata has to be preserved after all files are copied, children before parents
+	copiedDirs []*copyItem
+}
+
+// copyItem is something to be copied. Dirs and links are copied right away while walking, files later on. The names
+// are the ones within the file systems, the change has the paths to report.
+type copyItem struct {
+	change  Change
+	srcName string
+	dstName string
+	relPath string
+	srcInfo os.FileInfo
+	isFile  bool
+	done    bool
+}
+
+// report lists the changes of all items which have been done, in the order they have been walked
+func (c *dirCopier) report() *Report {
+	report := &Report{DryRun: c.options.DryRun}
+	for _, item := range c.items {
+		if item.done {
+			report.add(item.change)
+		}
+	}
+	return report
+}
+
+// pendingDir is a destination dir which is only created once something is copied into it, so that include patterns
+// don't leave a skeleton of empty dirs behind
+type pendingDir struct {
+	name    string
+	srcName string
+	mode    os.FileMode
+	parent  *pendingDir
+	created bool
+}
+
+// createDir creates the given dir and its pending parents, if they are not existing yet
+func (c *dirCopier) createDir(dir *pendingDir) error {
+	if dir == nil || dir.created {
+		return nil
+	}
+	if err := c.createDir(dir.parent); err != nil {
+		return err
+	}
+	if dstDirInfo, err := fs.Stat(c.dstFS, dir.name); err != nil || !dstDirInfo.IsDir() {
+		if !c.options.DryRun {
+			if err = c.dstFS.MkdirAll(dir.name, dir.mode); err != nil {
+				return err
+			}
+		}
+		change := Change{Kind: ChangeCreate, Path: fsPath(c.dstFS, dir.name), Source: fsPath(c.srcFS, dir.srcName),
+			IsDir: true}
+		c.items = append(c.items, &copyItem{change: change, done: true})
+	}
+	dir.created = true
+	return nil
+}
+
+// copyDir walks the dir's entries one by one. realAncestors holds the resolved paths of all dirs currently being
+// walked, which is what is needed to detect symlink loops.
+func (c *dirCopier) copyDir(srcDirName, dstDirName, relPath string, srcDirInfo os.FileInfo, realAncestors []string,
+	parentDir *pendingDir) error {
+	srcDirPath := fsPath(c.srcFS, srcDirName)
+	if c.options.Symlinks == SymlinksFollow {
+		realPath, err := realPathFS(c.srcFS, srcDirName)
+		if err != nil {
+			return wrapError("copy", srcDirPath, err)
+		}
+		if slices.Contains(realAncestors, realPath) {
+			return wrapError("copy", srcDirPath, ErrSymlinkLoop)
+		}
+		realAncestors = append(realAncestors, realPath)
+	}
+
+	dstDir := &pendingDir{name: dstDirName, srcName: srcDirName, mode: srcDirInfo.Mode().Perm(), parent: parentDir}
+	if relPath == "." || c.filter.includes(relPath, true) {
+		if err := c.createDir(dstDir); err != nil {
+			return wrapError("copy", srcDirPath, err)
+		}
+	}
+	dirEntries, err := fs.ReadDir(c.srcFS, srcDirName)
+	if err != nil {
+		return wrapError("copy", srcDirPath, err)
+	}
+
+	for _, dirEntry := range dirEntries {
+		if err = c.ctx.Err(); err != nil {
+			return err
+		}
+		srcName := path.Join(srcDirName, dirEntry.Name())
+		entryRelPath := path.Join(relPath, dirEntry.Name())
+		dstName, err := c.options.Template.renderName(entryRelPath, dirEntry.Name())
+		if err != nil {
+			return wrapError("copy", fsPath(c.srcFS, srcName), err)
+		}
+		if dstName == "" {
+			continue
+		}
+		if err = c.copyEntry(srcName, path.Join(dstDirName, dstName), entryRelPath, realAncestors, dstDir); err != nil {
+			return err
+		}
+	}
+
+	if dstDir.created {
+		change := Change{Path: fsPath(c.dstFS, dstDirName), Source: srcDirPath, IsDir: true}
+		c.copiedDirs = append(c.copiedDirs, &copyItem{change: change, srcName: srcDirName, dstName: dstDirName,
+			srcInfo: srcDirInfo})
+	}
+	return nil
+}
+
+func (c *dirCopier) copyEntry(srcName, dstName, relPath string, realAncestors []string, parentDir *pendingDir) error {
+	srcPath := fsPath(c.srcFS, srcName)
+	srcInfo, err := fs.Lstat(c.srcFS, srcName)
+	if err != nil {
+		return wrapError("copy", srcPath, err)
+	}
+
+	if srcInfo.Mode()&os.ModeSymlink != 0 {
+		switch c.options.Symlinks {
+		case SymlinksSkip:
+			return nil
+		case SymlinksPreserve:
+			if !c.filter.selects(relPath, false) {
+				return nil
+			}
+			if err = c.createDir(parentDir); err != nil {
+				return wrapError("copy", srcPath, err)
+			}
+			return c.copySymlink(c.newItem(srcName, dstName, relPath, srcInfo))
+		}
+		if srcInfo, err = fs.Stat(c.srcFS, srcName); err != nil {
+			return wrapError("copy", srcPath, err)
+		}
+	}
+
+	if !c.filter.selects(relPath, srcInfo.IsDir()) {
+		return nil
+	}
+	if srcInfo.IsDir() {
+		return c.copyDir(srcName, dstName, relPath, srcInfo, realAncestors, parentDir)
+	}
+	if !srcInfo.Mode().IsRegular() {
+		if c.options.SpecialFiles == SpecialFilesSkip {
+			return nil
+		}
+		return wrapError("copy", srcPath, ErrSpecialFile)
+	}
+
+	if err = c.createDir(parentDir); err != nil {
+		return wrapError("copy", srcPath, err)
+	}
+	item := c.newItem(srcName, dstName, relPath, srcInfo)
+	item.isFile = true
+	if c.skips(item) {
+		return nil
+	}
+	c.items = append(c.items, item)
+	return nil
+}
+
+// newItem creates the item for copying a file or link, whose change depends on the destination already existing
+func (c *dirCopier) newItem(srcName, dstName, relPath string, srcInfo os.FileInfo) *copyItem {
+	change := Change{Kind: ChangeCreate, Path: fsPath(c.dstFS, dstName), Source: fsPath(c.srcFS, srcName)}
+	if _, err := fs.Lstat(c.dstFS, dstName); err == nil {
+		change.Kind = ChangeOverwrite
+	}
+	return &copyItem{change: change, srcName: srcName, dstName: dstName, relPath: relPath, srcInfo: srcInfo}
+}
+
+// copyFiles copies all walked files, using as many workers as configured. The first error stops all workers.
+func (c *dirCopier) copyFiles() error {
+	var files []*copyItem
+	progress := CopyProgress{}
+	for _, item := range c.items {
+		if item.isFile {
+			files = append(files, item)
+			progress.FilesTotal++
+			progress.BytesTotal += item.srcInfo.Size()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	var mutex sync.Mutex
+	var firstErr error
+	var workers sync.WaitGroup
+	fileChannel := make(chan *copyItem)
+	for range max(c.options.Concurrency, 1) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for item := range fileChannel {
+				err := c.copyFile(ctx, item)
+				mutex.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				if err == nil && c.options.Progress != nil {
+					progress.FilesDone++
+					progress.BytesDone += item.srcInfo.Size()
+					progress.CurrentPath = item.change.Source
+					c.options.Progress(progress)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+feeding:
+	for _, item := range files {
+		select {
+		case fileChannel <- item:
+		case <-ctx.Done():
+			break feeding
+		}
+	}
+	close(fileChannel)
+	workers.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return c.ctx.Err()
+}
+
+// copyFile copies a single walked file - in a dry run, only the diff of the content changes is computed. Templates and
+// rules need the whole content in memory, everything else is streamed.
+func (c *dirCopier) copyFile(ctx context.Context, item *copyItem) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.options.DryRun {
+		diff, err := c.contentDiff(item)
+		if err != nil {
+			return wrapError("copy", item.change.Source, err)
+		}
+		item.change.Diff = diff
+		item.done = true
+		return nil
+	}
+
+	var err error
+	if c.options.Template != nil || c.rules != nil {
+		err = c.transformFile(ctx, item)
+	} else {
+		err = copyFileFS(ctx, c.srcFS, item.srcName, c.dstFS, item.dstName, c.replacer)
+	}
+	if err != nil {
+		return wrapError("copy", item.change.Source, err)
+	}
+	item.done = true
+	return c.preserveMetadata(item)
+}
+
+// transformFile writes the rendered or replaced content of the src file to dst, giving it the src file's mode
+func (c *dirCopier) transformFile(ctx context.Context, item *copyItem) error {
+	content, err := fs.ReadFile(c.srcFS, item.srcName)
+	if err != nil {
+		return err
+	}
+	if content, err = c.transformContent(ctx, item.relPath, content); err != nil {
+		return err
+	}
+	dstFileRef, err := c.dstFS.Create(item.dstName)
+	if err != nil {
+		return err
+	}
+	if _, err = dstFileRef.Write(content); err != nil {
+		_ = dstFileRef.Close()
+		return err
+	}
+	if err = dstFileRef.Close(); err != nil {
+		return err
+	}
+	return c.dstFS.Chmod(item.dstName, item.srcInfo.Mode())
+}
+
+// transformContent renders the content through the template or does the replacements and applies the rules
+func (c *dirCopier) transformContent(ctx context.Context, relPath string, content []byte) ([]byte, error) {
+	if c.options.Template != nil {
+		return c.options.Template.renderContent(relPath, content)
+	}
+	return replaceContent(ctx, content, c.replacer, c.rules)
+}
+
+// preserveDirMetadata is done last, as copying the files into the dirs has touched their modification times
+func (c *dirCopier) preserveDirMetadata() error {
+	for _, dir := range c.copiedDirs {
+		if err := c.preserveMetadata(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skips tells if an existing destination is up-to-date already
+func (c *dirCopier) skips(item *copyItem) bool {
+	return c.unchanged != nil && item.change.Kind == ChangeOverwrite &&
+		c.unchanged(item.change.Source, item.change.Path, item.srcInfo)
+}
+
+// contentDiff renders the content changes the replacements or the template would do to the file
+func (c *dirCopier) contentDiff(item *copyItem) (string, error) {
+	if c.options.Template == nil && c.replacer.empty() && c.rules == nil {
+		return "", nil
+	}
+	content, err := fs.ReadFile(c.srcFS, item.srcName)
+	if err != nil {
+		return "", err
+	}
+	newContent, err := c.transformContent(c.ctx, item.relPath, content)
+	if err != nil {
+		return "", err
+	}
+	return unifiedDiff(item.change.Source, item.change.Path, string(content), string(newContent)), nil
+}
+
+func (c *dirCopier) copySymlink(item *copyItem) error {
+	if c.skips(item) {
+		return nil
+	}
+	c.items = append(c.items, item)
+	if c.options.DryRun {
+		item.done = true
+		return nil
+	}
+	linkTarget, err := fs.ReadLink(c.srcFS, item.srcName)
+	if err != nil {
+		return wrapError("copy", item.change.Source, err)
+	}
+	// Symlink doesn't overwrite, so an existing entry has to go first - but not a whole dir
+	if dstInfo, err := fs.Lstat(c.dstFS, item.dstName); err == nil && !dstInfo.IsDir() {
+		if err = c.dstFS.RemoveAll(item.dstName); err != nil {
+			return wrapError("copy", item.change.Path, err)
+		}
+	}
+	if err = c.dstFS.(SymlinkFS).Symlink(linkTarget, item.dstName); err != nil {
+		return wrapError("copy", item.change.Source, err)
+	}
+	item.done = true
+	if c.options.PreserveOwner {
+		return wrapError("copy", item.change.Source, c.chownLike(item))
+	}
+	return nil
+}
+
+// copyFile copies a single file from src to dst on the OS, see copyFileFS
+func copyFile(ctx context.Context, srcFilePath, dstFilePath string, replacer *streamReplacer) error {
+	srcFS, srcName := osFileFS(srcFilePath)
+	dstFS, dstName := osFileFS(dstFilePath)
+	return copyFileFS(ctx, srcFS, srcName, dstFS, dstName, replacer)
+}
+
+// copyFileFS copies a single file, doing the replacements unless the file is binary. Plain copies between OS files are
+// done by cloneFile if possible, otherwise io.Copy uses copy_file_range on Linux.
+func copyFileFS(ctx context.Context, srcFS fs.FS, srcName string, dstFS FS, dstName string,
+	replacer *streamReplacer) error {
+	srcFileRef, err := srcFS.Open(srcName)
+	if err != nil {
+		return err
+	}
+	defer srcFileRef.Close()
+
+	dstFileRef, err := dstFS.Create(dstName)
+	if err != nil {
+		return err
+	}
+	defer dstFileRef.Close()
+
+	srcOSFile, srcIsOSFile := srcFileRef.(*os.File)
+	dstOSFile, dstIsOSFile := dstFileRef.(*os.File)
+	if !replacer.empty() || !srcIsOSFile || !dstIsOSFile || cloneFile(dstOSFile, srcOSFile) != nil {
+		if err = copyContent(ctx, dstFileRef, srcFileRef, replacer); err != nil {
+			return err
+		}
+	}
+	srcFileInfo, err := srcFileRef.Stat()
+	if err != nil {
+		return err
+	}
+	if err = dstFileRef.Close(); err != nil {
+		return err
+	}
+
+	return dstFS.Chmod(dstName, srcFileInfo.Mode())
+}
+
+// copyContent copies src to dst, applying the replacer unless the content turns out to be binary. The context is only
+// checked while replacing, plain copies are left to io.Copy, which can hand them over to the kernel.
+func copyContent(ctx context.Context, dst io.Writer, src io.Reader, replacer *streamReplacer) error {
+	if replacer.empty() {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+	bufferedSrc := bufio.NewReaderSize(src, sniffLen)
+	sample, err := bufferedSrc.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if isBinaryContent(sample) {
+		_, err = io.Copy(dst, bufferedSrc)
+		return err
+	}
+	_, err = replacer.replace(dst, contextReader{ctx: ctx, reader: bufferedSrc})
+	return err
+}
+
+// contextReader fails reading as soon as the context is done
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// preserveMetadata applies the optional ownership, xattr and time preservation to a copied file or dir
+func (c *dirCopier) preserveMetadata(item *copyItem) error {
+	if c.options.PreserveOwner {
+		if err := c.chownLike(item); err != nil {
+			return wrapError("copy", item.change.Source, err)
+		}
+		// Changing the owner can drop setuid and setgid bits
+		if err := c.dstFS.Chmod(item.dstName, item.srcInfo.Mode()); err != nil {
+			return wrapError("copy", item.change.Source, err)
+		}
+	}
+	if c.options.PreserveXattrs {
+		if err := copyXattrs(item.change.Source, item.change.Path); err != nil {
+			return wrapError("copy", item.change.Source, err)
+		}
+	}
+	if c.options.PreserveTimes {
+		if err := c.dstFS.(ChtimesFS).Chtimes(item.dstName, time.Time{}, item.srcInfo.ModTime()); err != nil {
+			return wrapError("copy", item.change.Source, err)
+		}
+	}
+	return nil
+}
+
+// chownLike gives the copy the owner and group of the source without following links
+func (c *dirCopier) chownLike(item *copyItem) error {
+	uid, gid, ok := fileOwner(item.srcInfo)
+	if !ok {
+		return fmt.Errorf("owner of the source unknown: %w", errors.ErrUnsupported)
+	}
+	return c.dstFS.(LchownFS).Lchown(item.dstName, uid, gid)
+}
@@ -0,0 +1,237 @@
+package file_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/investify-tech/go-utils/file"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createTree creates the given files (path -> content) below dirPath
+func createTree(t *testing.T, dirPath string, files map[string]string) {
+	t.Helper()
+	for filePath, content := range files {
+		fullPath := filepath.Join(dirPath, filepath.FromSlash(filePath))
+		file.CreateDir(filepath.Dir(fullPath))
+		file.WriteFile(fullPath, content, false)
+	}
+}
+
+func TestCopyDirWithOptionsSymlinks(test *testing.T) {
+	testCases := []struct {
+		name           string
+		symlinks       file.SymlinkMode
+		expectLink     bool
+		expectExisting bool
+	}{
+		{name: "follow", symlinks: file.SymlinksFollow, expectLink: false, expectExisting: true},
+		{name: "preserve", symlinks: file.SymlinksPreserve, expectLink: true, expectExisting: true},
+		{name: "skip", symlinks: file.SymlinksSkip, expectLink: false, expectExisting: false},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			srcDirPath := filepath.Join(t.TempDir(), "src")
+			dstDirPath := filepath.Join(t.TempDir(), "dst")
+			createTree(t, srcDirPath, map[string]string{"target.txt": "content"})
+			if err := os.Symlink("target.txt", filepath.Join(srcDirPath, "link.txt")); err != nil {
+				t.Skipf("Symlinks not supported: %v", err)
+			}
+
+			if err := file.CopyDirWithOptions(srcDirPath, dstDirPath, file.CopyOptions{Symlinks: testCase.symlinks}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			linkInfo, err := os.Lstat(filepath.Join(dstDirPath, "link.txt"))
+			if (err == nil) != testCase.expectExisting {
+				t.Fatalf("Expected link.txt to exist: %v, got error %v", testCase.expectExisting, err)
+			}
+			if err == nil && (linkInfo.Mode()&os.ModeSymlink != 0) != testCase.expectLink {
+				t.Errorf("Expected link.txt to be a symlink: %v, got mode %v", testCase.expectLink, linkInfo.Mode())
+			}
+		})
+	}
+}
+
+func TestCopyDirWithOptionsSymlinkLoop(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	createTree(test, srcDirPath, map[string]string{"sub/file.txt": "content"})
+	if err := os.Symlink("..", filepath.Join(srcDirPath, "sub", "parent")); err != nil {
+		test.Skipf("Symlinks not supported: %v", err)
+	}
+
+	err := file.CopyDirWithOptions(srcDirPath, filepath.Join(test.TempDir(), "dst"), file.CopyOptions{})
+	if !errors.Is(err, file.ErrSymlinkLoop) {
+		test.Errorf("Expected ErrSymlinkLoop but got %v", err)
+	}
+}
+
+func TestCopyDirWithOptionsPreserveTimes(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	createTree(test, srcDirPath, map[string]string{"sub/file.txt": "content"})
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, entryPath := range []string{"sub/file.txt", "sub"} {
+		if err := os.Chtimes(filepath.Join(srcDirPath, entryPath), modTime, modTime); err != nil {
+			test.Fatal(err)
+		}
+	}
+
+	if err := file.CopyDirWithOptions(srcDirPath, dstDirPath, file.CopyOptions{PreserveTimes: true}); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, entryPath := range []string{"sub/file.txt", "sub"} {
+		entryInfo, err := os.Stat(filepath.Join(dstDirPath, entryPath))
+		if err != nil {
+			test.Fatal(err)
+		}
+		if !entryInfo.ModTime().Equal(modTime) {
+			test.Errorf("Expected %s to have mod time %v but got %v", entryPath, modTime, entryInfo.ModTime())
+		}
+	}
+}
+
+func TestCopyDirWithOptionsFilter(test *testing.T) {
+	testCases := []struct {
+		name     string
+		options  file.FilterOptions
+		expected []string
+	}{
+		{
+			name:     "exclude",
+			options:  file.FilterOptions{Exclude: []string{".git/", "node_modules/", "*.log"}},
+			expected: []string{"README.md", "src", "src/main.go", "src/util", "src/util/util.go"},
+		},
+		{
+			name:     "include",
+			options:  file.FilterOptions{Include: []string{"src/**/*.go"}},
+			expected: []string{"src", "src/main.go", "src/util", "src/util/util.go"},
+		},
+		{
+			name:     "include with negated exclude",
+			options:  file.FilterOptions{Include: []string{"*.go"}, Exclude: []string{"util/", "!util/"}},
+			expected: []string{"src", "src/main.go", "src/util", "src/util/util.go"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			srcDirPath := filepath.Join(t.TempDir(), "src")
+			dstDirPath := filepath.Join(t.TempDir(), "dst")
+			createTree(t, srcDirPath, map[string]string{
+				".git/HEAD":               "ref",
+				"node_modules/x/index.js": "js",
+				"README.md":               "readme",
+				"debug.log":               "log",
+				"src/main.go":             "package main",
+				"src/util/util.go":        "package util",
+			})
+
+			if err := file.CopyDirWithOptions(srcDirPath, dstDirPath, file.CopyOptions{FilterOptions: testCase.options}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result := listTree(t, dstDirPath); !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("Expected %v but got %v", testCase.expected, result)
+			}
+		})
+	}
+}
+
+func TestCopyDirAndRenameFilesWithOptions(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	createTree(test, srcDirPath, map[string]string{
+		"app.go":           "package app",
+		"app_test.go":      "package app",
+		"build/app.binary": "binary",
+	})
+
+	options := file.CopyOptions{FilterOptions: file.FilterOptions{Include: []string{"*.go"}, Exclude: []string{"*_test.go"}}}
+	err := file.CopyDirAndRenameFilesWithOptions(srcDirPath, dstDirPath, map[string]string{"app": "service"}, options)
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"service.go"}
+	if result := listTree(test, dstDirPath); !reflect.DeepEqual(result, expected) {
+		test.Errorf("Expected %v but got %v", expected, result)
+	}
+}
+
+// listTree returns the sorted, slash separated paths of all entries below dirPath
+func listTree(t *testing.T, dirPath string) []string {
+	t.Helper()
+	var relPaths []string
+	err := filepath.WalkDir(dirPath, func(entryPath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil || entryPath == dirPath {
+			return err
+		}
+		relPath, err := filepath.Rel(dirPath, entryPath)
+		relPaths = append(relPaths, filepath.ToSlash(relPath))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(relPaths)
+	return relPaths
+}
+
+func TestCopyDirContextConcurrency(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	files := make(map[string]string)
+	for i := range 50 {
+		files[fmt.Sprintf("dir%d/file%d.txt", i%5, i)] = fmt.Sprintf("content NAME %d", i)
+	}
+	createTree(test, srcDirPath, files)
+
+	var progresses []file.CopyProgress
+	options := file.CopyOptions{
+		Replacements: map[string]string{"NAME": "go-utils"},
+		Concurrency:  8,
+		Progress:     func(progress file.CopyProgress) { progresses = append(progresses, progress) },
+	}
+	report, err := file.CopyDirContext(context.Background(), srcDirPath, dstDirPath, options)
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	for relPath, content := range files {
+		expected := strings.ReplaceAll(content, "NAME", "go-utils")
+		if result := file.ReadFile(filepath.Join(dstDirPath, relPath)); result != expected {
+			test.Errorf("Expected %s to contain %q but got %q", relPath, expected, result)
+		}
+	}
+	if len(report.Changes) != 1+5+50 {
+		test.Errorf("Expected 56 changes but got %d", len(report.Changes))
+	}
+	lastProgress := progresses[len(progresses)-1]
+	if len(progresses) != 50 || lastProgress.FilesDone != 50 || lastProgress.FilesTotal != 50 ||
+		lastProgress.BytesDone != lastProgress.BytesTotal {
+		test.Errorf("Expected 50 progress calls ending complete but got %d ending with %+v", len(progresses), lastProgress)
+	}
+}
+
+func TestCopyDirContextCancelled(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	createTree(test, srcDirPath, map[string]string{"a.txt": "a", "b.txt": "b"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := file.CopyDirContext(ctx, srcDirPath, filepath.Join(test.TempDir(), "dst"), file.CopyOptions{})
+
+	if !errors.Is(err, context.Canceled) {
+		test.Errorf("Expected context.Canceled but got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package file_test
+
+import (
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestCopyDirWithOptionsSpecialFiles(test *testing.T) {
+	testCases := []struct {
+		name         string
+		specialFiles file.SpecialFileMode
+		expectedErr  error
+	}{
+		{name: "error", specialFiles: file.SpecialFilesError, expectedErr: file.ErrSpecialFile},
+		{name: "skip", specialFiles: file.SpecialFilesSkip, expectedErr: nil},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			srcDirPath := t.TempDir()
+			dstDirPath := filepath.Join(t.TempDir(), "dst")
+			if err := syscall.Mkfifo(filepath.Join(srcDirPath, "pipe"), 0644); err != nil {
+				t.Skipf("FIFOs not supported: %v", err)
+			}
+
+			err := file.CopyDirWithOptions(srcDirPath, dstDirPath, file.CopyOptions{SpecialFiles: testCase.specialFiles})
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Errorf("Expected %v but got %v", testCase.expectedErr, err)
+			}
+			if file.Exists(filepath.Join(dstDirPath, "pipe")) {
+				t.Errorf("Expected the FIFO not to be copied")
+			}
+		})
+	}
+}
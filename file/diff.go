@@ -0,0 +1,166 @@
+package file
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is the number of unchanged lines shown around changes, as diff -u does by default
+const diffContextLines = 3
+
+type diffOp struct {
+	kind byte // ' ' for unchanged, '-' for removed and '+' for added lines
+	line string
+}
+
+// unifiedDiff returns the changes between two texts in unified diff format, "" if there are none
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	ops := diffLines(splitDiffLines(oldText), splitDiffLines(newText))
+
+	var builder strings.Builder
+	builder.WriteString("--- " + oldName + "\n")
+	builder.WriteString("+++ " + newName + "\n")
+	for start := 0; start < len(ops); {
+		// Find the next change and extend the hunk as long as changes are close enough to each other
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		hunkStart := max(start-diffContextLines, 0)
+		hunkEnd := start
+		unchanged := 0
+		for i := start; i < len(ops) && unchanged <= 2*diffContextLines; i++ {
+			if ops[i].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+				hunkEnd = i + 1
+			}
+		}
+		hunkEnd = min(hunkEnd+diffContextLines, len(ops))
+		writeDiffHunk(&builder, ops, hunkStart, hunkEnd)
+		start = hunkEnd
+	}
+	return builder.String()
+}
+
+func writeDiffHunk(builder *strings.Builder, ops []diffOp, hunkStart, hunkEnd int) {
+	// Line numbers are 1-based and count the lines before the hunk
+	oldLine, newLine := 1, 1
+	for _, op := range ops[:hunkStart] {
+		if op.kind != '+' {
+			oldLine++
+		}
+		if op.kind != '-' {
+			newLine++
+		}
+	}
+	oldCount, newCount := 0, 0
+	for _, op := range ops[hunkStart:hunkEnd] {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+	// Empty ranges point to the line before, as diff -u does
+	if oldCount == 0 {
+		oldLine--
+	}
+	if newCount == 0 {
+		newLine--
+	}
+
+	fmt.Fprintf(builder, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+	for _, op := range ops[hunkStart:hunkEnd] {
+		builder.WriteByte(op.kind)
+		builder.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			builder.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// splitDiffLines splits a text into lines keeping the line breaks
+func splitDiffLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script with the Myers algorithm
+func diffLines(oldLines, newLines []string) []diffOp {
+	oldLen, newLen := len(oldLines), len(newLines)
+	maxDist := oldLen + newLen
+	offset := maxDist + 1
+	frontier := make([]int, 2*maxDist+3)
+	var trace [][]int
+
+search:
+	for dist := 0; dist <= maxDist; dist++ {
+		// Only the diagonals reachable in this round are needed for walking back later
+		trace = append(trace, append([]int(nil), frontier[offset-dist-1:offset+dist+2]...))
+		for diagonal := -dist; diagonal <= dist; diagonal += 2 {
+			var x int
+			if diagonal == -dist || (diagonal != dist && frontier[offset+diagonal-1] < frontier[offset+diagonal+1]) {
+				x = frontier[offset+diagonal+1]
+			} else {
+				x = frontier[offset+diagonal-1] + 1
+			}
+			y := x - diagonal
+			for x < oldLen && y < newLen && oldLines[x] == newLines[y] {
+				x++
+				y++
+			}
+			frontier[offset+diagonal] = x
+			if x >= oldLen && y >= newLen {
+				break search
+			}
+		}
+	}
+
+	// Walk back through the trace, collecting the ops in reverse order
+	var ops []diffOp
+	x, y := oldLen, newLen
+	for dist := len(trace) - 1; dist >= 0; dist-- {
+		previousFrontier, traceOffset := trace[dist], dist+1
+		diagonal := x - y
+		var previousDiagonal int
+		if diagonal == -dist ||
+			(diagonal != dist && previousFrontier[traceOffset+diagonal-1] < previousFrontier[traceOffset+diagonal+1]) {
+			previousDiagonal = diagonal + 1
+		} else {
+			previousDiagonal = diagonal - 1
+		}
+		previousX := previousFrontier[traceOffset+previousDiagonal]
+		previousY := previousX - previousDiagonal
+		for x > previousX && y > previousY {
+			ops = append(ops, diffOp{' ', oldLines[x-1]})
+			x--
+			y--
+		}
+		if dist > 0 {
+			if x == previousX {
+				ops = append(ops, diffOp{'+', newLines[y-1]})
+				y--
+			} else {
+				ops = append(ops, diffOp{'-', oldLines[x-1]})
+				x--
+			}
+		}
+		x, y = previousX, previousY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,151 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// envKeyPattern matches the keys accepted in dotenv files
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// envBareValuePattern matches the values which can be written without quotes
+var envBareValuePattern = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+
+// ReadEnv reads a dotenv file. Supported are "KEY=value" lines with an optional "export " prefix, blank lines and
+// "#" comments. Values may be unquoted (trailing " #" comments are cut off), single-quoted (taken literally) or
+// double-quoted (with "\n", "\r", "\t", "\"" and "\\" escapes). Later definitions of a key win. Variables aren't
+// expanded.
+func ReadEnv(filePath string) (map[string]string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, wrapError("read env", filePath, err)
+	}
+	values, err := parseEnv(content)
+	return values, wrapError("read env", filePath, err)
+}
+
+// WriteEnv writes the values as dotenv file sorted by key, quoting values where needed, atomically like
+// WriteFileAtomic. The rights of an existing file are kept.
+func WriteEnv(filePath string, values map[string]string) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if !envKeyPattern.MatchString(key) {
+			return wrapError("write env", filePath, fmt.Errorf("invalid key %q", key))
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var content bytes.Buffer
+	for _, key := range keys {
+		content.WriteString(key + "=" + formatEnvValue(values[key]) + "\n")
+	}
+	return wrapError("write env", filePath, writeFileAtomicKeepingMode(filePath, content.Bytes()))
+}
+
+func parseEnv(content []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	for lineIndex, line := range strings.Split(string(content), "\n") {
+		key, value, isEntry, err := parseEnvLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineIndex+1, err)
+		}
+		if isEntry {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+// parseEnvLine parses a single line, isEntry is false for blank and comment lines
+func parseEnvLine(line string) (key, value string, isEntry bool, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false, nil
+	}
+	key, value, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+	key = strings.TrimSpace(key)
+	if !found || !envKeyPattern.MatchString(key) {
+		return "", "", false, fmt.Errorf("invalid entry %q", line)
+	}
+	value, err = parseEnvValue(strings.TrimSpace(value))
+	return key, value, true, err
+}
+
+func parseEnvValue(rawValue string) (string, error) {
+	if rawValue == "" {
+		return "", nil
+	}
+	switch quote := rawValue[0]; quote {
+	case '\'':
+		end := strings.IndexByte(rawValue[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quote in %s", rawValue)
+		}
+		return rawValue[1 : end+1], nil
+	case '"':
+		var value strings.Builder
+		for i := 1; i < len(rawValue); i++ {
+			char := rawValue[i]
+			if char == '"' {
+				return value.String(), nil
+			}
+			if char == '\\' && i+1 < len(rawValue) {
+				i++
+				char = map[byte]byte{'n': '\n', 'r': '\r', 't': '\t'}[rawValue[i]]
+				if char == 0 {
+					char = rawValue[i]
+				}
+			}
+			value.WriteByte(char)
+		}
+		return "", fmt.Errorf("unterminated quote in %s", rawValue)
+	}
+	if commentStart := strings.Index(rawValue, " #"); commentStart >= 0 {
+		rawValue = rawValue[:commentStart]
+	}
+	return strings.TrimSpace(rawValue), nil
+}
+
+// formatEnvValue quotes a value unless it only contains harmless characters
+func formatEnvValue(value string) string {
+	if envBareValuePattern.MatchString(value) {
+		return value
+	}
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + escaper.Replace(value) + `"`
+}
+
+// setEnvValue replaces the last definition of the key, keeping an "export " prefix, or appends it. All other lines stay
+// as they are.
+func setEnvValue(content []byte, key, value string) ([]byte, error) {
+	if !envKeyPattern.MatchString(key) {
+		return nil, fmt.Errorf("invalid key %q", key)
+	}
+	if _, err := parseEnv(content); err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(content), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		lineKey, _, isEntry, _ := parseEnvLine(lines[i])
+		if !isEntry || lineKey != key {
+			continue
+		}
+		prefix, lineEnd := "", ""
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "export ") {
+			prefix = "export "
+		}
+		if strings.HasSuffix(lines[i], "\r") {
+			lineEnd = "\r"
+		}
+		lines[i] = prefix + key + "=" + formatEnvValue(value) + lineEnd
+		return []byte(strings.Join(lines, "\n")), nil
+	}
+	if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		content = append(content, '\n')
+	}
+	return append(content, []byte(key+"="+formatEnvValue(value)+"\n")...), nil
+}
@@ -0,0 +1,89 @@
+package file_test
+
+import (
+	"github.com/investify-tech/go-utils/file"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadEnv(test *testing.T) {
+	testCases := []struct {
+		name      string
+		content   string
+		expected  map[string]string
+		expectErr bool
+	}{
+		{
+			name:     "plain",
+			content:  "A=1\r\nB = two \n\n# comment\nexport C=3\n",
+			expected: map[string]string{"A": "1", "B": "two", "C": "3"},
+		},
+		{
+			name:     "quotes",
+			content:  "A='single $HOME \\n'\nB=\"double\\n\\\"quoted\\\"\" # comment\nC=unquoted # comment\nD=\n",
+			expected: map[string]string{"A": "single $HOME \\n", "B": "double\n\"quoted\"", "C": "unquoted", "D": ""},
+		},
+		{
+			name:     "last wins",
+			content:  "A=1\nA=2\n",
+			expected: map[string]string{"A": "2"},
+		},
+		{name: "missing equals", content: "A\n", expectErr: true},
+		{name: "invalid key", content: "1A=1\n", expectErr: true},
+		{name: "unterminated quote", content: "A=\"open\n", expectErr: true},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), ".env")
+			file.WriteFile(filePath, testCase.content, false)
+
+			result, err := file.ReadEnv(filePath)
+			if (err != nil) != testCase.expectErr {
+				t.Fatalf("Expected error: %v, got %v", testCase.expectErr, err)
+			}
+			if !testCase.expectErr && !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("Expected %q, got %q", testCase.expected, result)
+			}
+		})
+	}
+}
+
+func TestWriteEnvRoundTrip(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "app.env")
+	values := map[string]string{"PLAIN": "value", "URL": "https://host:8080/path", "SPACED": "a b", "MULTI": "line1\nline2",
+		"QUOTE": `say "hi" \o/`, "EMPTY": ""}
+
+	if err := file.WriteEnv(filePath, values); err != nil {
+		test.Fatalf("Unexpected error writing: %v", err)
+	}
+	result, err := file.ReadEnv(filePath)
+	if err != nil {
+		test.Fatalf("Unexpected error reading: %v", err)
+	}
+
+	if !reflect.DeepEqual(result, values) {
+		test.Errorf("Expected %q, got %q", values, result)
+	}
+	if content := file.ReadFile(filePath); content[:len("EMPTY=\n")] != "EMPTY=\n" {
+		test.Errorf("Expected sorted keys, got:\n%s", content)
+	}
+}
+
+func TestReadConfigEnv(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), ".env.local")
+	file.WriteFile(filePath, "name=service\nport=8080\n", false)
+
+	result, err := file.ReadConfig[struct {
+		Name string `json:"name"`
+		Port string `json:"port"`
+	}](filePath)
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.Name != "service" || result.Port != "8080" {
+		test.Errorf("Unexpected result %+v", result)
+	}
+}
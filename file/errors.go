@@ -0,0 +1,42 @@
+package file
+
+import "errors"
+
+// ErrSymlinkLoop is reported when following a symlink leads back into one of the dirs it is placed in
+var ErrSymlinkLoop = errors.New("symlink loop detected")
+
+// ErrSpecialFile is reported for FIFOs, sockets and device files which can't be copied like regular files
+var ErrSpecialFile = errors.New("special file")
+
+// ErrLineTooLong is reported by the line readers for lines exceeding LineOptions.MaxLineLength
+var ErrLineTooLong = errors.New("line too long")
+
+// ErrProtectedDir is reported by CleanDirWithOptions for dirs it refuses to clean
+var ErrProtectedDir = errors.New("protected dir")
+
+// ErrUnknownConfigFormat is reported when the config format can't be derived from the file name
+var ErrUnknownConfigFormat = errors.New("unknown config format")
+
+// OpError records a failed file operation together with the path it was working on. The underlying error is kept, so
+// errors.Is(err, fs.ErrNotExist) and friends still work.
+type OpError struct {
+	Op   string
+	Path string
+	Err  error
+}
+
+func (e *OpError) Error() string {
+	return e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// wrapError wraps err into an OpError - nil stays nil so that it can be used directly on return values
+func wrapError(op string, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &OpError{Op: op, Path: path, Err: err}
+}
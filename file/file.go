@@ -1,16 +1,13 @@
 package file
 
 import (
+	"context"
 	"github.com/investify-tech/go-utils/must"
-	"io"
-	"io/ioutil"
 	"os"
 	"path"
-	"path/filepath"
-	"strings"
 )
 
-// Exists checks if a file or dir is existing
+// Exists checks if a file or dir is existing - errors other than a missing entry count as existing, see ExistsE
 func Exists(fileOrDirPath string) bool {
 	if _, err := os.Stat(fileOrDirPath); err != nil {
 		if os.IsNotExist(err) {
@@ -22,26 +19,44 @@ func Exists(fileOrDirPath string) bool {
 
 // WriteFile writes a file and encapsulates error check and file rights setting
 func WriteFile(dstFilePath string, fileContent string, executable bool) {
-	var fileMode os.FileMode
+	must.Void(WriteFileE(dstFilePath, fileContent, executable))
+}
+
+// WriteFileE is the error returning variant of WriteFile
+func WriteFileE(dstFilePath string, fileContent string, executable bool) error {
+	contentBytes := []byte(fileContent)
+	return wrapError("write file", dstFilePath, os.WriteFile(dstFilePath, contentBytes, fileModeFor(executable)))
+}
+
+// fileModeFor returns the rights used for files written by this package
+func fileModeFor(executable bool) os.FileMode {
 	if executable {
-		fileMode = 0755
-	} else {
-		fileMode = 0644
+		return 0755
 	}
-	contentBytes := []byte(fileContent)
-	must.Void(os.WriteFile(dstFilePath, contentBytes, fileMode))
+	return 0644
 }
 
 // ReadFile reads and returns the content of a file and encapsulates error handling
 func ReadFile(filePath string) string {
-	fileBytes := must.AnySlice(os.ReadFile(filePath))
-	return string(fileBytes)
+	return must.String(ReadFileE(filePath))
+}
+
+// ReadFileE is the error returning variant of ReadFile
+func ReadFileE(filePath string) (string, error) {
+	fsys, name := osFileFS(filePath)
+	return ReadFileFS(fsys, name)
 }
 
 // ReadFileLines ReadFile reads and returns the content of a file as an arrays of lines and encapsulates error handling
 func ReadFileLines(filePath string) []string {
-	fileBytes := must.AnySlice(os.ReadFile(filePath))
-	return strings.Split(string(fileBytes), "\n")
+	return must.AnySlice(ReadFileLinesE(filePath))
+}
+
+// ReadFileLinesE is the error returning variant of ReadFileLines. Lines may end with "\n" or "\r\n", a final line
+// break doesn't produce an empty last line. Use FileLines for files which shouldn't be read into memory at once.
+func ReadFileLinesE(filePath string) ([]string, error) {
+	fsys, name := osFileFS(filePath)
+	return ReadFileLinesFS(fsys, name)
 }
 
 // CopyFile copies a single file from src to dst
@@ -50,35 +65,10 @@ func CopyFile(srcPath, dstPath string) error {
 }
 
 // CopyFileAndReplaceContent copies a single file from src to dst and does replacements in the file's content
-// as provided in the given map
+// as provided in the given map. All replacements are done in a single pass, with overlapping keys the longest one wins.
+// Binary files (like images or archives) are copied as they are.
 func CopyFileAndReplaceContent(srcFilePath, dstFilePath string, replacements map[string]string) error {
-	var err error
-	var srcFileRef *os.File
-	var dstFileRef *os.File
-	var srcFileInfo os.FileInfo
-
-	if srcFileRef, err = os.Open(srcFilePath); err != nil {
-		return err
-	}
-	defer srcFileRef.Close()
-
-	if dstFileRef, err = os.Create(dstFilePath); err != nil {
-		return err
-	}
-	defer dstFileRef.Close()
-
-	if _, err = io.Copy(dstFileRef, srcFileRef); err != nil {
-		return err
-	}
-	if srcFileInfo, err = os.Stat(srcFilePath); err != nil {
-		return err
-	}
-
-	for old, new := range replacements {
-		ReplaceFileContent(dstFilePath, old, new)
-	}
-
-	return os.Chmod(dstFilePath, srcFileInfo.Mode())
+	return copyFile(context.Background(), srcFilePath, dstFilePath, newStreamReplacer(replacements))
 }
 
 // CopyDir copies a whole directory recursively
@@ -89,92 +79,83 @@ func CopyDir(srcDirPath, dstDirPath string) error {
 // CopyDirAndReplaceContentInFiles copies a whole directory recursively and does replacements in all files' contents
 // as provided in the given map
 func CopyDirAndReplaceContentInFiles(srcDirPath, dstDirPath string, replacements map[string]string) error {
-	var err error
-	var srcDirContent []os.FileInfo
-	var srcDirInfo os.FileInfo
-
-	if srcDirInfo, err = os.Stat(srcDirPath); err != nil {
-		return err
-	}
-	if err = os.MkdirAll(dstDirPath, srcDirInfo.Mode()); err != nil {
-		return err
-	}
-	if srcDirContent, err = ioutil.ReadDir(srcDirPath); err != nil {
-		return err
-	}
-
-	for _, fileInfo := range srcDirContent {
-		srcPath := path.Join(srcDirPath, fileInfo.Name())
-		dstPath := path.Join(dstDirPath, fileInfo.Name())
-
-		if fileInfo.IsDir() {
-			must.Void(CopyDirAndReplaceContentInFiles(srcPath, dstPath, replacements))
-		} else {
-			must.Void(CopyFileAndReplaceContent(srcPath, dstPath, replacements))
-		}
-	}
-	return nil
+	return CopyDirWithOptions(srcDirPath, dstDirPath, CopyOptions{Replacements: replacements})
 }
 
+// CopyDirAndRenameFiles copies a whole directory recursively and renames the copied files and dirs as
+// RenameFilesInDir does
 func CopyDirAndRenameFiles(srcDirPath, dstDirPath string, replacements map[string]string) error {
-	err := CopyDir(srcDirPath, dstDirPath)
-	if err != nil {
-		return err
-	}
-
-	err = RenameFilesInDir(dstDirPath, replacements)
-	return err
+	return CopyDirAndRenameFilesWithOptions(srcDirPath, dstDirPath, replacements, CopyOptions{})
 }
 
+// RenameFilesInDir replaces all occurrences of the given map's keys in the names of the files and dirs within the
+// given directory (see RenameOptions)
 func RenameFilesInDir(dirPath string, replacements map[string]string) error {
-	err := filepath.Walk(dirPath, func(path string, f os.FileInfo, err error) error {
-		fileName := f.Name()
-		for key, val := range replacements {
-			if strings.Contains(fileName, key) {
-				dir := filepath.Dir(path)
-				newFileName := strings.Replace(fileName, key, val, 1)
-				newFilePath := filepath.Join(dir, newFileName)
-				os.Rename(path, newFilePath)
-			}
-		}
-		return nil
-	})
-	return err
+	return RenameFilesInDirWithOptions(dirPath, RenameOptions{Replacements: replacements})
 }
 
-// ReplaceFileContent replaces value in the file's content by replacement
+// ReplaceFileContent replaces value in the file's content by replacement, see ReplaceFileContentWithRules for regular
+// expressions
 func ReplaceFileContent(filePath string, value string, replacement string) {
-	input := must.AnySlice(os.ReadFile(filePath))
-	fileInfo, err := os.Stat(filePath)
-	must.Void(err)
-
-	output := strings.ReplaceAll(string(input), value, replacement)
+	must.Void(ReplaceFileContentE(filePath, value, replacement))
+}
 
-	must.Void(os.WriteFile(filePath, []byte(output), fileInfo.Mode().Perm()))
+// ReplaceFileContentE is the error returning variant of ReplaceFileContent
+func ReplaceFileContentE(filePath string, value string, replacement string) error {
+	fsys, name := osFileFS(filePath)
+	return ReplaceFileContentFS(fsys, name, value, replacement)
 }
 
-// CleanDir "cleans" the given directory by deleting and recreating it with "allow everything" rights
+// CleanDir "cleans" the given directory by deleting and recreating it with rights 0755 (see CleanDirWithOptions for
+// the paths it refuses to clean)
 func CleanDir(dirPath string) {
-	must.Void(os.RemoveAll(dirPath))
-	must.Void(os.MkdirAll(dirPath, 0777))
+	must.Void(CleanDirE(dirPath))
+}
+
+// CleanDirE is the error returning variant of CleanDir
+func CleanDirE(dirPath string) error {
+	return CleanDirWithOptions(dirPath, CleanOptions{})
 }
 
 // CleanDirFromFiles "cleans" the given directory from files, i.e. deletes all files (but no directories) from it
 func CleanDirFromFiles(dirPath string) {
-	for _, dirEntry := range must.AnySlice(os.ReadDir(dirPath)) {
+	must.Void(CleanDirFromFilesE(dirPath))
+}
+
+// CleanDirFromFilesE is the error returning variant of CleanDirFromFiles
+func CleanDirFromFilesE(dirPath string) error {
+	dirEntries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return wrapError("clean dir from files", dirPath, err)
+	}
+	for _, dirEntry := range dirEntries {
 		if dirEntry.IsDir() {
 			continue
 		}
-		must.Void(os.Remove(path.Join(dirPath, dirEntry.Name())))
+		filePath := path.Join(dirPath, dirEntry.Name())
+		if err = os.Remove(filePath); err != nil {
+			return wrapError("clean dir from files", filePath, err)
+		}
 	}
+	return nil
 }
 
 // CreateDir creates the given directory with "allow everything" rights
 func CreateDir(dirPath string) {
-	must.Void(os.MkdirAll(dirPath, 0777))
+	must.Void(CreateDirE(dirPath))
+}
+
+// CreateDirE is the error returning variant of CreateDir
+func CreateDirE(dirPath string) error {
+	return wrapError("create dir", dirPath, os.MkdirAll(dirPath, 0777))
 }
 
 // RemoveDir removes the given directory - just there for convenience
 func RemoveDir(dirPath string) {
-	must.Void(os.RemoveAll(dirPath))
+	must.Void(RemoveDirE(dirPath))
+}
+
+// RemoveDirE is the error returning variant of RemoveDir
+func RemoveDirE(dirPath string) error {
+	return wrapError("remove dir", dirPath, os.RemoveAll(dirPath))
 }
@@ -0,0 +1,114 @@
+package file_test
+
+import (
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadFileLinesE(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "lines.txt")
+	file.WriteFile(filePath, "one\r\ntwo\nthree\n", false)
+
+	result, err := file.ReadFileLinesE(filePath)
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"one", "two", "three"}
+	if !reflect.DeepEqual(result, expected) {
+		test.Errorf("Expected %v but got %v", expected, result)
+	}
+}
+
+func TestErrorReturningVariants(test *testing.T) {
+	missingPath := filepath.Join(test.TempDir(), "missing", "file.txt")
+
+	testCases := []struct {
+		name       string
+		call       func() error
+		expectedOp string
+	}{
+		{
+			name: "ReadFileE",
+			call: func() error {
+				_, err := file.ReadFileE(missingPath)
+				return err
+			},
+			expectedOp: "read file",
+		},
+		{
+			name: "ReadFileLinesE",
+			call: func() error {
+				_, err := file.ReadFileLinesE(missingPath)
+				return err
+			},
+			expectedOp: "read file lines",
+		},
+		{
+			name:       "WriteFileE",
+			call:       func() error { return file.WriteFileE(missingPath, "content", false) },
+			expectedOp: "write file",
+		},
+		{
+			name:       "ReplaceFileContentE",
+			call:       func() error { return file.ReplaceFileContentE(missingPath, "a", "b") },
+			expectedOp: "replace file content",
+		},
+		{
+			name:       "CleanDirFromFilesE",
+			call:       func() error { return file.CleanDirFromFilesE(missingPath) },
+			expectedOp: "clean dir from files",
+		},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			err := testCase.call()
+			var opError *file.OpError
+			if !errors.As(err, &opError) {
+				t.Fatalf("Expected an OpError but got %v", err)
+			}
+			if opError.Op != testCase.expectedOp || opError.Path != missingPath {
+				t.Errorf("Expected op %q on %q but got %q on %q", testCase.expectedOp, missingPath, opError.Op, opError.Path)
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Expected the error to wrap fs.ErrNotExist but got %v", err)
+			}
+		})
+	}
+}
+
+func TestDirFunctionsE(test *testing.T) {
+	dirPath := filepath.Join(test.TempDir(), "a", "b")
+
+	if err := file.CreateDirE(dirPath); err != nil {
+		test.Fatalf("CreateDirE failed: %v", err)
+	}
+	file.WriteFile(filepath.Join(dirPath, "file.txt"), "content", false)
+	file.CreateDir(filepath.Join(dirPath, "sub"))
+
+	if err := file.CleanDirFromFilesE(dirPath); err != nil {
+		test.Fatalf("CleanDirFromFilesE failed: %v", err)
+	}
+	if file.Exists(filepath.Join(dirPath, "file.txt")) || !file.Exists(filepath.Join(dirPath, "sub")) {
+		test.Errorf("Expected only files to be removed")
+	}
+
+	if err := file.CleanDirE(dirPath); err != nil {
+		test.Fatalf("CleanDirE failed: %v", err)
+	}
+	if dirEntries, _ := os.ReadDir(dirPath); len(dirEntries) != 0 {
+		test.Errorf("Expected an empty dir but got %d entries", len(dirEntries))
+	}
+
+	if err := file.RemoveDirE(dirPath); err != nil {
+		test.Fatalf("RemoveDirE failed: %v", err)
+	}
+	if file.Exists(dirPath) {
+		test.Errorf("Expected %s to be removed", dirPath)
+	}
+}
@@ -0,0 +1,200 @@
+package file
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// FilterOptions selects the entries of a directory tree to work on. Both lists take gitignore-style patterns:
+//   - "*", "?" and "[...]" match within a single path segment, "**" matches across segments
+//   - a pattern containing a "/" is anchored at the tree's root, otherwise it matches at any depth
+//   - a trailing "/" only matches directories
+//   - a leading "!" negates the pattern, i.e. re-includes what an earlier pattern excluded (the last match wins)
+//
+// An excluded directory is not descended into. If Include is not empty, only files matching one of its patterns (or
+// lying in a directory matching one of them) are selected - directories are still descended into, as they might
+// contain selected files.
+type FilterOptions struct {
+	Include []string
+	Exclude []string
+}
+
+// Filter is the compiled form of FilterOptions
+type Filter struct {
+	include []filterPattern
+	exclude []filterPattern
+}
+
+type filterPattern struct {
+	regex   *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// NewFilter compiles the given filter options and reports invalid patterns
+func NewFilter(options FilterOptions) (*Filter, error) {
+	var err error
+	filter := &Filter{}
+	if filter.include, err = compileFilterPatterns(options.Include); err != nil {
+		return nil, err
+	}
+	if filter.exclude, err = compileFilterPatterns(options.Exclude); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// Match tells if the entry with the given slash separated path (relative to the tree's root) is selected by the filter,
+// taking into account that an excluded parent directory excludes everything below it
+func (f *Filter) Match(relPath string, isDir bool) bool {
+	relPath = strings.Trim(relPath, "/")
+	if relPath == "" || relPath == "." {
+		return true
+	}
+	segments := strings.Split(relPath, "/")
+	for i := 1; i < len(segments); i++ {
+		if f.excludes(strings.Join(segments[:i], "/"), true) {
+			return false
+		}
+	}
+	return f.selects(relPath, isDir)
+}
+
+// selects checks the entry itself without its parents - walkers don't need more, as they don't descend into excluded
+// dirs. Dirs are selected unless excluded, the include patterns are only checked for files.
+func (f *Filter) selects(relPath string, isDir bool) bool {
+	if f.excludes(relPath, isDir) {
+		return false
+	}
+	return isDir || f.includes(relPath, false)
+}
+
+// selectsEntry is selects for walkers reporting dirs as entries of their own: with include patterns, dirs are
+// descended into, but only selected themselves if they match one of them
+func (f *Filter) selectsEntry(relPath string, isDir bool) bool {
+	return f.selects(relPath, isDir) && (!isDir || f.includes(relPath, true))
+}
+
+// excludes checks the entry against the exclude patterns
+func (f *Filter) excludes(relPath string, isDir bool) bool {
+	return f != nil && matchFilterPatterns(f.exclude, relPath, isDir)
+}
+
+// includes checks if the entry itself or one of its parent directories matches the include patterns
+func (f *Filter) includes(relPath string, isDir bool) bool {
+	if f.includesAll() {
+		return true
+	}
+	if matchFilterPatterns(f.include, relPath, isDir) {
+		return true
+	}
+	for dirPath := path.Dir(relPath); dirPath != "."; dirPath = path.Dir(dirPath) {
+		if matchFilterPatterns(f.include, dirPath, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// includesAll tells if there are no include patterns which could deselect anything
+func (f *Filter) includesAll() bool {
+	return f == nil || len(f.include) == 0
+}
+
+// matchFilterPatterns applies the patterns in order, the last matching one decides
+func matchFilterPatterns(patterns []filterPattern, relPath string, isDir bool) bool {
+	matched := false
+	for _, pattern := range patterns {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+		if pattern.regex.MatchString(relPath) {
+			matched = !pattern.negate
+		}
+	}
+	return matched
+}
+
+func compileFilterPatterns(rawPatterns []string) ([]filterPattern, error) {
+	var patterns []filterPattern
+	for _, rawPattern := range rawPatterns {
+		pattern, ok, err := compileFilterPattern(rawPattern)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns, nil
+}
+
+// compileFilterPattern translates a gitignore-style pattern into a regex. Empty patterns and comments ("# ...") are
+// skipped, so that the lines of a .gitignore file can be passed as they are.
+func compileFilterPattern(rawPattern string) (filterPattern, bool, error) {
+	var pattern filterPattern
+	glob := strings.TrimSpace(rawPattern)
+	if glob == "" || strings.HasPrefix(glob, "#") {
+		return pattern, false, nil
+	}
+	if strings.HasPrefix(glob, "!") {
+		pattern.negate = true
+		glob = glob[1:]
+	}
+	if strings.HasSuffix(glob, "/") {
+		pattern.dirOnly = true
+		glob = strings.TrimRight(glob, "/")
+	}
+	anchored := strings.Contains(glob, "/")
+	glob = strings.TrimPrefix(glob, "/")
+	if glob == "" {
+		return pattern, false, fmt.Errorf("invalid filter pattern '%s'", rawPattern)
+	}
+
+	var regex strings.Builder
+	regex.WriteString("^")
+	if !anchored {
+		regex.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(glob); i++ {
+		atSegmentStart := i == 0 || glob[i-1] == '/'
+		switch {
+		case atSegmentStart && strings.HasPrefix(glob[i:], "**/"):
+			regex.WriteString("(?:.*/)?")
+			i += 2
+		case atSegmentStart && glob[i:] == "**":
+			regex.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			regex.WriteString("[^/]*")
+		case glob[i] == '?':
+			regex.WriteString("[^/]")
+		case glob[i] == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return pattern, false, fmt.Errorf("invalid filter pattern '%s': unterminated character class", rawPattern)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			regex.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case glob[i] == '\\' && i+1 < len(glob):
+			i++
+			regex.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			regex.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	regex.WriteString("$")
+
+	compiledRegex, err := regexp.Compile(regex.String())
+	if err != nil {
+		return pattern, false, fmt.Errorf("invalid filter pattern '%s': %w", rawPattern, err)
+	}
+	pattern.regex = compiledRegex
+	return pattern, true, nil
+}
@@ -0,0 +1,54 @@
+package file_test
+
+import (
+	"github.com/investify-tech/go-utils/file"
+	"testing"
+)
+
+func TestFilterMatch(test *testing.T) {
+	testCases := []struct {
+		name     string
+		options  file.FilterOptions
+		relPath  string
+		isDir    bool
+		expected bool
+	}{
+		{"no patterns", file.FilterOptions{}, "a/b.txt", false, true},
+		{"basename at any depth", file.FilterOptions{Exclude: []string{"*.log"}}, "a/b/c.log", false, false},
+		{"basename not matching", file.FilterOptions{Exclude: []string{"*.log"}}, "a/b/c.txt", false, true},
+		{"excluded parent dir", file.FilterOptions{Exclude: []string{"node_modules/"}}, "x/node_modules/y.js", false, false},
+		{"dir only pattern on file", file.FilterOptions{Exclude: []string{"build/"}}, "build", false, true},
+		{"anchored pattern", file.FilterOptions{Exclude: []string{"/build"}}, "sub/build", true, true},
+		{"anchored pattern at root", file.FilterOptions{Exclude: []string{"/build"}}, "build", true, false},
+		{"double star in the middle", file.FilterOptions{Exclude: []string{"a/**/z.txt"}}, "a/b/c/z.txt", false, false},
+		{"double star matching no dir", file.FilterOptions{Exclude: []string{"a/**/z.txt"}}, "a/z.txt", false, false},
+		{"trailing double star", file.FilterOptions{Exclude: []string{"dist/**"}}, "dist/js/app.js", false, false},
+		{"negation", file.FilterOptions{Exclude: []string{"*.log", "!keep.log"}}, "a/keep.log", false, true},
+		{"negation overridden", file.FilterOptions{Exclude: []string{"!keep.log", "*.log"}}, "keep.log", false, false},
+		{"character class", file.FilterOptions{Exclude: []string{"file[0-9].txt"}}, "file7.txt", false, false},
+		{"comment is ignored", file.FilterOptions{Exclude: []string{"# *.txt"}}, "a.txt", false, true},
+		{"include matching", file.FilterOptions{Include: []string{"**/*.go"}}, "cmd/main.go", false, true},
+		{"include not matching", file.FilterOptions{Include: []string{"**/*.go"}}, "README.md", false, false},
+		{"include by dir", file.FilterOptions{Include: []string{"templates/"}}, "templates/a/b.tmpl", false, true},
+		{"dirs pass include", file.FilterOptions{Include: []string{"*.go"}}, "cmd", true, true},
+		{"exclude beats include", file.FilterOptions{Include: []string{"*.go"}, Exclude: []string{"vendor/"}}, "vendor/x.go", false, false},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			filter, err := file.NewFilter(testCase.options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result := filter.Match(testCase.relPath, testCase.isDir); result != testCase.expected {
+				t.Errorf("Expected %v but got %v", testCase.expected, result)
+			}
+		})
+	}
+}
+
+func TestNewFilterInvalidPattern(test *testing.T) {
+	if _, err := file.NewFilter(file.FilterOptions{Exclude: []string{"file[0-9"}}); err == nil {
+		test.Errorf("Expected an error for an unterminated character class")
+	}
+}
@@ -0,0 +1,251 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FindOptions configures Find. All criteria have to match, the zero value finds all files.
+type FindOptions struct {
+	// Pattern is a glob matched against the slash separated path relative to the searched dir, see Glob
+	Pattern string
+	// FilterOptions additionally select entries by gitignore-style patterns, excluded dirs aren't searched at all
+	FilterOptions
+	// Dirs finds dirs, too, otherwise only files (including symlinks and special files) are found
+	Dirs bool
+	// MinSize and MaxSize limit the size in bytes, zero means no limit
+	MinSize int64
+	MaxSize int64
+	// ModifiedAfter and ModifiedBefore limit the modification time, the zero time means no limit
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	// Match is an optional predicate for everything else, like the mode
+	Match func(path string, info fs.FileInfo) bool
+	// Grep only finds regular text files whose content matches, the matches are part of the result
+	Grep *GrepOptions
+}
+
+// FindResult is an entry found by Find
+type FindResult struct {
+	Path string
+	// RelPath is the slash separated path relative to the searched dir
+	RelPath string
+	Info    fs.FileInfo
+	// Matches are the matching lines if FindOptions.Grep is set
+	Matches []GrepMatch
+}
+
+// GrepOptions configures searching a file's content
+type GrepOptions struct {
+	// Pattern is searched literally in every line, or as a regular expression if Regex is set
+	Pattern    string
+	Regex      bool
+	IgnoreCase bool
+	// MaxMatches stops searching a file after this number of matching lines, zero means no limit
+	MaxMatches int
+}
+
+// GrepMatch is a matching line
+type GrepMatch struct {
+	// Line is the 1-based line number
+	Line int
+	// Text is the line without its line ending
+	Text string
+}
+
+// Glob returns the paths of all files and dirs below dirPath (sorted, dirPath itself not included) whose slash
+// separated path relative to dirPath matches the pattern. "*", "?" and "[...]" match within a path segment, "**"
+// matches any number of segments, e.g. "**/*.go" finds all Go files and "cmd/**" everything below cmd.
+func Glob(dirPath string, pattern string) ([]string, error) {
+	var paths []string
+	for result, err := range Find(dirPath, FindOptions{Pattern: pattern, Dirs: true}) {
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, result.Path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Find walks the tree below dirPath in lexical order and yields all entries matching the options. Symlinks aren't
+// followed. Stopping the iteration stops the walk, so searching for the first match doesn't walk the whole tree. An
+// error is yielded together with an empty result and ends the iteration.
+func Find(dirPath string, options FindOptions) iter.Seq2[FindResult, error] {
+	return func(yield func(FindResult, error) bool) {
+		finder, err := newFinder(options)
+		if err != nil {
+			yield(FindResult{}, wrapError("find", dirPath, err))
+			return
+		}
+		stopped := false
+		err = walkFiltered(dirPath, finder.filter, func(entryPath, relPath string, dirEntry fs.DirEntry) error {
+			result, found, err := finder.check(entryPath, relPath, dirEntry)
+			if err != nil {
+				return err
+			}
+			if found && !yield(result, nil) {
+				stopped = true
+				return filepath.SkipAll
+			}
+			return nil
+		})
+		if err != nil && !stopped {
+			yield(FindResult{}, wrapError("find", dirPath, err))
+		}
+	}
+}
+
+// FindAll collects the results of Find, stopping after maxResults if it isn't zero
+func FindAll(dirPath string, options FindOptions, maxResults int) ([]FindResult, error) {
+	var results []FindResult
+	for result, err := range Find(dirPath, options) {
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+		if len(results) == maxResults {
+			break
+		}
+	}
+	return results, nil
+}
+
+// Grep returns the lines of a file matching the options. Binary files never match.
+func Grep(filePath string, options GrepOptions) ([]GrepMatch, error) {
+	matcher, err := newGrepMatcher(options)
+	if err != nil {
+		return nil, wrapError("grep", filePath, err)
+	}
+	matches, err := matcher.grep(filePath)
+	return matches, wrapError("grep", filePath, err)
+}
+
+type finder struct {
+	options FindOptions
+	filter  *Filter
+	pattern *filterPattern
+	grep    *grepMatcher
+}
+
+func newFinder(options FindOptions) (*finder, error) {
+	filter, err := NewFilter(options.FilterOptions)
+	if err != nil {
+		return nil, err
+	}
+	finder := &finder{options: options, filter: filter}
+	if options.Pattern != "" {
+		// A leading "/" anchors the pattern at the searched dir, unlike gitignore patterns without "/"
+		pattern, ok, err := compileFilterPattern("/" + strings.TrimPrefix(options.Pattern, "/"))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			finder.pattern = &pattern
+		}
+	}
+	if options.Grep != nil {
+		if finder.grep, err = newGrepMatcher(*options.Grep); err != nil {
+			return nil, err
+		}
+	}
+	return finder, nil
+}
+
+// check applies all criteria to a walked entry, the cheap ones first
+func (f *finder) check(entryPath, relPath string, dirEntry fs.DirEntry) (FindResult, bool, error) {
+	result := FindResult{Path: entryPath, RelPath: relPath}
+	if dirEntry.IsDir() && !f.options.Dirs {
+		return result, false, nil
+	}
+	if f.pattern != nil && !matchFilterPatterns([]filterPattern{*f.pattern}, relPath, dirEntry.IsDir()) {
+		return result, false, nil
+	}
+	info, err := dirEntry.Info()
+	if err != nil {
+		return result, false, err
+	}
+	result.Info = info
+	if (f.options.MinSize > 0 && info.Size() < f.options.MinSize) ||
+		(f.options.MaxSize > 0 && info.Size() > f.options.MaxSize) ||
+		(!f.options.ModifiedAfter.IsZero() && !info.ModTime().After(f.options.ModifiedAfter)) ||
+		(!f.options.ModifiedBefore.IsZero() && !info.ModTime().Before(f.options.ModifiedBefore)) {
+		return result, false, nil
+	}
+	if f.options.Match != nil && !f.options.Match(entryPath, info) {
+		return result, false, nil
+	}
+	if f.grep != nil {
+		if !info.Mode().IsRegular() {
+			return result, false, nil
+		}
+		if result.Matches, err = f.grep.grep(entryPath); err != nil {
+			return result, false, err
+		}
+		return result, len(result.Matches) > 0, nil
+	}
+	return result, true, nil
+}
+
+type grepMatcher struct {
+	regex      *regexp.Regexp
+	maxMatches int
+}
+
+func newGrepMatcher(options GrepOptions) (*grepMatcher, error) {
+	pattern := options.Pattern
+	if !options.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if options.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grep pattern '%s': %w", options.Pattern, err)
+	}
+	return &grepMatcher{regex: regex, maxMatches: options.MaxMatches}, nil
+}
+
+func (m *grepMatcher) grep(filePath string) ([]GrepMatch, error) {
+	fileRef, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer fileRef.Close()
+	sample := make([]byte, sniffLen)
+	sampleLen, err := io.ReadFull(fileRef, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if isBinaryContent(sample[:sampleLen]) {
+		return nil, nil
+	}
+	if _, err = fileRef.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var matches []GrepMatch
+	lineNumber := 0
+	for line, err := range Lines(fileRef, LineOptions{MaxLineLength: -1}) {
+		if err != nil {
+			return nil, err
+		}
+		lineNumber++
+		if m.regex.MatchString(line) {
+			matches = append(matches, GrepMatch{Line: lineNumber, Text: line})
+			if len(matches) == m.maxMatches {
+				break
+			}
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,205 @@
+package file_test
+
+import (
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGlob(test *testing.T) {
+	dirPath := test.TempDir()
+	createTree(test, dirPath, map[string]string{
+		"main.go":           "package main",
+		"README.md":         "readme",
+		"cmd/tool/tool.go":  "package tool",
+		"cmd/tool/tool.md":  "tool",
+		"internal/x/x.go":   "package x",
+		"internal/x/x_test": "test",
+	})
+
+	testCases := []struct {
+		pattern  string
+		expected []string
+	}{
+		{pattern: "*.go", expected: []string{"main.go"}},
+		{pattern: "**/*.go", expected: []string{"cmd/tool/tool.go", "internal/x/x.go", "main.go"}},
+		{pattern: "cmd/**", expected: []string{"cmd/tool", "cmd/tool/tool.go", "cmd/tool/tool.md"}},
+		{pattern: "*/*/x?go", expected: []string{"internal/x/x.go"}},
+		{pattern: "**/*.txt", expected: nil},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.pattern, func(t *testing.T) {
+			paths, err := file.Glob(dirPath, testCase.pattern)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var relPaths []string
+			for _, foundPath := range paths {
+				relPath, _ := filepath.Rel(dirPath, foundPath)
+				relPaths = append(relPaths, filepath.ToSlash(relPath))
+			}
+			if !reflect.DeepEqual(relPaths, testCase.expected) {
+				t.Errorf("Expected %v but got %v", testCase.expected, relPaths)
+			}
+		})
+	}
+}
+
+func TestFindAll(test *testing.T) {
+	dirPath := test.TempDir()
+	createTree(test, dirPath, map[string]string{
+		"small.txt":         "a",
+		"large.txt":         "0123456789",
+		"old.txt":           "0123456789",
+		"script.sh":         "#!/bin/sh",
+		"vendor/lib/lib.go": "package lib",
+	})
+	oldTime := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dirPath, "old.txt"), oldTime, oldTime); err != nil {
+		test.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(dirPath, "script.sh"), 0755); err != nil {
+		test.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		options  file.FindOptions
+		expected []string
+	}{
+		{name: "all files", options: file.FindOptions{}, expected: []string{"large.txt", "old.txt", "script.sh", "small.txt", "vendor/lib/lib.go"}},
+		{name: "dirs", options: file.FindOptions{Dirs: true, Pattern: "vendor/**"}, expected: []string{"vendor/lib", "vendor/lib/lib.go"}},
+		{name: "size", options: file.FindOptions{Pattern: "*.txt", MinSize: 5}, expected: []string{"large.txt", "old.txt"}},
+		{name: "max size", options: file.FindOptions{MaxSize: 1}, expected: []string{"small.txt"}},
+		{name: "modified", options: file.FindOptions{Pattern: "*.txt", ModifiedBefore: time.Now().Add(-time.Hour)}, expected: []string{"old.txt"}},
+		{
+			name:     "filter",
+			options:  file.FindOptions{FilterOptions: file.FilterOptions{Exclude: []string{"vendor/", "*.txt"}}},
+			expected: []string{"script.sh"},
+		},
+		{
+			name: "mode",
+			options: file.FindOptions{Match: func(path string, info fs.FileInfo) bool {
+				return info.Mode().Perm()&0100 != 0
+			}},
+			expected: []string{"script.sh"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			results, err := file.FindAll(dirPath, testCase.options, 0)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var relPaths []string
+			for _, result := range results {
+				relPaths = append(relPaths, result.RelPath)
+			}
+			if !reflect.DeepEqual(relPaths, testCase.expected) {
+				t.Errorf("Expected %v but got %v", testCase.expected, relPaths)
+			}
+		})
+	}
+}
+
+func TestFindGrep(test *testing.T) {
+	dirPath := test.TempDir()
+	createTree(test, dirPath, map[string]string{
+		"a.go":       "package a\n\n// TODO: fix\nfunc A() {}\n// todo later\n",
+		"b.go":       "package b\n",
+		"c.txt":      "TODO elsewhere\n",
+		"binary.bin": "TODO\x00\x01",
+	})
+
+	testCases := []struct {
+		name     string
+		options  file.FindOptions
+		expected map[string][]file.GrepMatch
+	}{
+		{
+			name:     "literal",
+			options:  file.FindOptions{Pattern: "*.go", Grep: &file.GrepOptions{Pattern: "TODO:"}},
+			expected: map[string][]file.GrepMatch{"a.go": {{Line: 3, Text: "// TODO: fix"}}},
+		},
+		{
+			name:    "ignore case",
+			options: file.FindOptions{Grep: &file.GrepOptions{Pattern: "todo", IgnoreCase: true}},
+			expected: map[string][]file.GrepMatch{
+				"a.go":  {{Line: 3, Text: "// TODO: fix"}, {Line: 5, Text: "// todo later"}},
+				"c.txt": {{Line: 1, Text: "TODO elsewhere"}},
+			},
+		},
+		{
+			name:     "regex with max matches",
+			options:  file.FindOptions{Grep: &file.GrepOptions{Pattern: `^(package|func) \w`, Regex: true, MaxMatches: 1}},
+			expected: map[string][]file.GrepMatch{"a.go": {{Line: 1, Text: "package a"}}, "b.go": {{Line: 1, Text: "package b"}}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			results, err := file.FindAll(dirPath, testCase.options, 0)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			matches := make(map[string][]file.GrepMatch)
+			for _, result := range results {
+				matches[result.RelPath] = result.Matches
+			}
+			if !reflect.DeepEqual(matches, testCase.expected) {
+				t.Errorf("Expected %v but got %v", testCase.expected, matches)
+			}
+		})
+	}
+}
+
+func TestFindEarlyTermination(test *testing.T) {
+	dirPath := test.TempDir()
+	createTree(test, dirPath, map[string]string{"a/1.txt": "1", "b/2.txt": "2", "c/3.txt": "3"})
+
+	visited := 0
+	options := file.FindOptions{Match: func(path string, info fs.FileInfo) bool {
+		visited++
+		return true
+	}}
+	for result, err := range file.Find(dirPath, options) {
+		if err != nil {
+			test.Fatalf("Unexpected error: %v", err)
+		}
+		if result.RelPath != "a/1.txt" {
+			test.Errorf("Expected a/1.txt first but got %s", result.RelPath)
+		}
+		break
+	}
+	if visited != 1 {
+		test.Errorf("Expected the walk to stop after the first result but visited %d files", visited)
+	}
+
+	results, err := file.FindAll(dirPath, file.FindOptions{}, 2)
+	if err != nil || len(results) != 2 {
+		test.Errorf("Expected 2 results but got %d, error %v", len(results), err)
+	}
+}
+
+func TestFindErrors(test *testing.T) {
+	_, err := file.FindAll(test.TempDir(), file.FindOptions{Grep: &file.GrepOptions{Pattern: "(", Regex: true}}, 0)
+	var opError *file.OpError
+	if !errors.As(err, &opError) || opError.Op != "find" {
+		test.Errorf("Expected a find OpError for an invalid regex but got %v", err)
+	}
+
+	missingPath := filepath.Join(test.TempDir(), "missing")
+	if _, err = file.Glob(missingPath, "*"); !errors.Is(err, fs.ErrNotExist) {
+		test.Errorf("Expected fs.ErrNotExist but got %v", err)
+	}
+	if _, err = file.Grep(missingPath, file.GrepOptions{Pattern: "x"}); !errors.Is(err, fs.ErrNotExist) {
+		test.Errorf("Expected fs.ErrNotExist but got %v", err)
+	}
+}
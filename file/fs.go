@@ -0,0 +1,397 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrReadOnlyFS is reported when writing to a file system wrapped by ReadOnlyFS
+var ErrReadOnlyFS = errors.New("read-only file system")
+
+// FS is a writable file system. Reading works like io/fs, so an FS can be used wherever an fs.FS is expected, and all
+// names follow the io/fs rules: slash separated, unrooted, without "." or ".." elements (see fs.ValidPath), with "."
+// being the root itself. Implementations are OSFS, MemFS and ReadOnlyFS (e.g. for embed.FS). Symlinks are read via
+// fs.ReadLinkFS, the optional interfaces SymlinkFS, ChtimesFS and LchownFS add what copying needs for preserving
+// links, times and owners.
+type FS interface {
+	fs.StatFS
+	// MkdirAll creates a dir together with all missing parents, like os.MkdirAll
+	MkdirAll(name string, perm fs.FileMode) error
+	// WriteFile creates or truncates a file, like os.WriteFile - the parent dir has to exist
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	// Create creates or truncates a file for writing its content as a stream, like os.Create - the parent dir has to
+	// exist. Depending on the implementation, the content may only be visible after closing.
+	Create(name string) (io.WriteCloser, error)
+	// Chmod changes the rights of a file or dir, including the setuid, setgid and sticky bits, like os.Chmod
+	Chmod(name string, mode fs.FileMode) error
+	// Rename moves a file or dir, replacing an existing file, like os.Rename
+	Rename(oldName, newName string) error
+	// RemoveAll removes a file or a dir with all its content, a missing entry is fine, like os.RemoveAll
+	RemoveAll(name string) error
+}
+
+// SymlinkFS is an FS which can create symlinks, like os.Symlink
+type SymlinkFS interface {
+	FS
+	Symlink(target, name string) error
+}
+
+// ChtimesFS is an FS which can change the access and modification times, like os.Chtimes
+type ChtimesFS interface {
+	FS
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+}
+
+// LchownFS is an FS which can change owner and group without following symlinks, like os.Lchown
+type LchownFS interface {
+	FS
+	Lchown(name string, uid, gid int) error
+}
+
+// OSFS returns the FS of the OS below the given dir, which supports all optional interfaces. Like os.DirFS, it doesn't
+// prevent symlinks from pointing out of the dir.
+func OSFS(dirPath string) FS {
+	return &osFS{fsys: os.DirFS(dirPath), dirPath: dirPath}
+}
+
+// osFileFS returns the OSFS of a file's dir together with the file's name in it, so that the OS path based functions
+// can use the FS based ones
+func osFileFS(filePath string) (*osFS, string) {
+	return &osFS{fsys: os.DirFS(filepath.Dir(filePath)), dirPath: filepath.Dir(filePath)}, filepath.Base(filePath)
+}
+
+type osFS struct {
+	fsys    fs.FS
+	dirPath string
+}
+
+func (f *osFS) Open(name string) (fs.File, error) {
+	return f.fsys.Open(name)
+}
+
+func (f *osFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(f.fsys, name)
+}
+
+func (f *osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(f.fsys, name)
+}
+
+func (f *osFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(f.fsys, name)
+}
+
+// ReadLink returns the target as it is, unlike os.DirFS, which refuses absolute ones
+func (f *osFS) ReadLink(name string) (string, error) {
+	osPath, err := f.osPath("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	return os.Readlink(osPath)
+}
+
+func (f *osFS) Lstat(name string) (fs.FileInfo, error) {
+	osPath, err := f.osPath("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(osPath)
+}
+
+func (f *osFS) MkdirAll(name string, perm fs.FileMode) error {
+	osPath, err := f.osPath("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(osPath, perm)
+}
+
+func (f *osFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	osPath, err := f.osPath("write", name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(osPath, data, perm)
+}
+
+// Create returns an *os.File, which lets copies use cloneFile and copy_file_range
+func (f *osFS) Create(name string) (io.WriteCloser, error) {
+	osPath, err := f.osPath("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Create(osPath)
+}
+
+func (f *osFS) Chmod(name string, mode fs.FileMode) error {
+	osPath, err := f.osPath("chmod", name)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(osPath, mode)
+}
+
+func (f *osFS) Symlink(target, name string) error {
+	osPath, err := f.osPath("symlink", name)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(target, osPath)
+}
+
+func (f *osFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	osPath, err := f.osPath("chtimes", name)
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(osPath, atime, mtime)
+}
+
+func (f *osFS) Lchown(name string, uid, gid int) error {
+	osPath, err := f.osPath("lchown", name)
+	if err != nil {
+		return err
+	}
+	return os.Lchown(osPath, uid, gid)
+}
+
+func (f *osFS) Rename(oldName, newName string) error {
+	oldPath, err := f.osPath("rename", oldName)
+	if err != nil {
+		return err
+	}
+	newPath, err := f.osPath("rename", newName)
+	if err != nil {
+		return err
+	}
+	return os.Rename(oldPath, newPath)
+}
+
+func (f *osFS) RemoveAll(name string) error {
+	osPath, err := f.osPath("remove", name)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(osPath)
+}
+
+// osPath checks the name and maps it to the OS path
+func (f *osFS) osPath(op string, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(f.dirPath, filepath.FromSlash(name)), nil
+}
+
+// fsPath is the path of an entry shown in reports and errors: the OS path for OSFS, the name for all others
+func fsPath(fsys fs.FS, name string) string {
+	if osFS, ok := fsys.(*osFS); ok {
+		return filepath.Join(osFS.dirPath, filepath.FromSlash(name))
+	}
+	return name
+}
+
+// realPathFS resolves all symlinks in a name like filepath.EvalSymlinks. Beyond the OS, links pointing out of the file
+// system are invalid.
+func realPathFS(fsys fs.FS, name string) (string, error) {
+	if osFS, ok := fsys.(*osFS); ok {
+		osPath, err := osFS.osPath("evalsymlinks", name)
+		if err != nil {
+			return "", err
+		}
+		return filepath.EvalSymlinks(osPath)
+	}
+	if _, ok := fsys.(fs.ReadLinkFS); !ok {
+		return name, nil
+	}
+	resolved := "."
+	remaining := strings.Split(name, "/")
+	for links := 0; len(remaining) > 0; {
+		element := remaining[0]
+		remaining = remaining[1:]
+		switch {
+		case element == "." || element == "":
+			continue
+		case element == "..":
+			if resolved == "." {
+				return "", &fs.PathError{Op: "evalsymlinks", Path: name, Err: fs.ErrInvalid}
+			}
+			resolved = path.Dir(resolved)
+			continue
+		}
+		next := path.Join(resolved, element)
+		fileInfo, err := fs.Lstat(fsys, next)
+		if err != nil {
+			return "", err
+		}
+		if fileInfo.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		// the limit of Linux
+		if links++; links > 40 {
+			return "", &fs.PathError{Op: "evalsymlinks", Path: name, Err: ErrSymlinkLoop}
+		}
+		linkTarget, err := fs.ReadLink(fsys, next)
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(linkTarget) {
+			return "", &fs.PathError{Op: "evalsymlinks", Path: name, Err: fs.ErrInvalid}
+		}
+		remaining = append(strings.Split(linkTarget, "/"), remaining...)
+	}
+	return resolved, nil
+}
+
+// ReadOnlyFS turns any fs.FS (like an embed.FS) into an FS failing all writes with ErrReadOnlyFS
+func ReadOnlyFS(fsys fs.FS) FS {
+	return readOnlyFS{fsys}
+}
+
+type readOnlyFS struct {
+	fsys fs.FS
+}
+
+func (f readOnlyFS) Open(name string) (fs.File, error) {
+	return f.fsys.Open(name)
+}
+
+func (f readOnlyFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(f.fsys, name)
+}
+
+func (f readOnlyFS) ReadLink(name string) (string, error) {
+	return fs.ReadLink(f.fsys, name)
+}
+
+func (f readOnlyFS) Lstat(name string) (fs.FileInfo, error) {
+	return fs.Lstat(f.fsys, name)
+}
+
+func (f readOnlyFS) MkdirAll(name string, perm fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: ErrReadOnlyFS}
+}
+
+func (f readOnlyFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return &fs.PathError{Op: "write", Path: name, Err: ErrReadOnlyFS}
+}
+
+func (f readOnlyFS) Create(name string) (io.WriteCloser, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: ErrReadOnlyFS}
+}
+
+func (f readOnlyFS) Chmod(name string, mode fs.FileMode) error {
+	return &fs.PathError{Op: "chmod", Path: name, Err: ErrReadOnlyFS}
+}
+
+func (f readOnlyFS) Rename(oldName, newName string) error {
+	return &fs.PathError{Op: "rename", Path: oldName, Err: ErrReadOnlyFS}
+}
+
+func (f readOnlyFS) RemoveAll(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: ErrReadOnlyFS}
+}
+
+// ReadFileFS is ReadFileE for an fs.FS
+func ReadFileFS(fsys fs.FS, name string) (string, error) {
+	fileBytes, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", wrapError("read file", fsPath(fsys, name), err)
+	}
+	return string(fileBytes), nil
+}
+
+// ReadFileLinesFS is ReadFileLinesE for an fs.FS
+func ReadFileLinesFS(fsys fs.FS, name string) ([]string, error) {
+	fileRef, err := fsys.Open(name)
+	if err != nil {
+		return nil, wrapError("read file lines", fsPath(fsys, name), err)
+	}
+	defer fileRef.Close()
+	lines := []string{}
+	for line, err := range Lines(fileRef, LineOptions{MaxLineLength: -1}) {
+		if err != nil {
+			return nil, wrapError("read file lines", fsPath(fsys, name), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// WriteFileFS is WriteFileE for an FS
+func WriteFileFS(fsys FS, name string, fileContent string, executable bool) error {
+	err := fsys.WriteFile(name, []byte(fileContent), fileModeFor(executable))
+	return wrapError("write file", fsPath(fsys, name), err)
+}
+
+// ReplaceFileContentFS is ReplaceFileContentE for an FS
+func ReplaceFileContentFS(fsys FS, name string, value string, replacement string) error {
+	_, err := rewriteFileFS(fsys, name, func(content []byte) ([]byte, int) {
+		return []byte(strings.ReplaceAll(string(content), value, replacement)), 1
+	})
+	return wrapError("replace file content", fsPath(fsys, name), err)
+}
+
+// ReplaceFileContentWithRulesFS is ReplaceFileContentWithRules for an FS, which rewrites the file in place instead of
+// atomically
+func ReplaceFileContentWithRulesFS(fsys FS, name string, rules ...ReplaceRule) (int, error) {
+	compiledRules, err := compileReplaceRules(rules)
+	if err != nil {
+		return 0, wrapError("replace file content", fsPath(fsys, name), err)
+	}
+	count, err := rewriteFileFS(fsys, name, compiledRules.apply)
+	return count, wrapError("replace file content", fsPath(fsys, name), err)
+}
+
+// rewriteFileFS replaces the file's content by the result of update, keeping its rights. The file isn't written if
+// update reports zero changes.
+func rewriteFileFS(fsys FS, name string, update func(content []byte) ([]byte, int)) (int, error) {
+	fileInfo, err := fs.Stat(fsys, name)
+	if err != nil {
+		return 0, err
+	}
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return 0, err
+	}
+	content, count := update(content)
+	if count == 0 {
+		return 0, nil
+	}
+	return count, fsys.WriteFile(name, content, fileInfo.Mode().Perm())
+}
+
+// RenameFilesInDirFS is RenameFilesInDirWithReport for an FS
+func RenameFilesInDirFS(fsys FS, dirPath string, options RenameOptions) (*Report, error) {
+	renamer, err := newDirRenamer(fsys, dirPath, options)
+	if err != nil {
+		return &Report{DryRun: options.DryRun}, err
+	}
+	entries, err := renamer.listEntries()
+	if err != nil {
+		return &Report{DryRun: options.DryRun}, err
+	}
+	return renamer.rename(entries)
+}
+
+// CopyDirFS is CopyDirWithReport from any fs.FS (like an embed.FS) to an FS, see CopyDirFSContext
+func CopyDirFS(srcFS fs.FS, srcDirPath string, dstFS FS, dstDirPath string, options CopyOptions) (*Report, error) {
+	return CopyDirFSContext(context.Background(), srcFS, srcDirPath, dstFS, dstDirPath, options)
+}
+
+// CopyDirFSContext is CopyDirContext from any fs.FS to an FS, which is what all dir copies of this package come down to.
+// Options the file systems can't support fail with errors.ErrUnsupported: SymlinksPreserve needs a SymlinkFS,
+// PreserveTimes a ChtimesFS, PreserveOwner an LchownFS and PreserveXattrs the OSFS on both sides. Sources which don't
+// implement fs.ReadLinkFS have no symlinks to preserve or skip.
+func CopyDirFSContext(ctx context.Context, srcFS fs.FS, srcDirPath string, dstFS FS, dstDirPath string,
+	options CopyOptions) (*Report, error) {
+	return copyDir(ctx, srcFS, srcDirPath, dstFS, dstDirPath, options, nil)
+}
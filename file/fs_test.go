@@ -0,0 +1,252 @@
+package file_test
+
+import (
+	"embed"
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+//go:embed testdata/embedded
+var embeddedFS embed.FS
+
+func TestMemFS(test *testing.T) {
+	memFS := file.NewMemFSWithFiles(map[string]string{"a/b.txt": "b", "c.txt": "c"})
+
+	if err := memFS.MkdirAll("x/y", 0755); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	if err := memFS.WriteFile("x/y/z.txt", []byte("z"), 0600); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	if err := memFS.WriteFile("missing/z.txt", []byte("z"), 0600); !errors.Is(err, fs.ErrNotExist) {
+		test.Errorf("Expected fs.ErrNotExist writing into a missing dir, got %v", err)
+	}
+	if err := memFS.Rename("a", "x/y/a"); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	if err := memFS.RemoveAll("c.txt"); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	if err := fstest.TestFS(memFS, "x/y/z.txt", "x/y/a/b.txt"); err != nil {
+		test.Errorf("MemFS doesn't behave like an fs.FS: %v", err)
+	}
+
+	if content, err := file.ReadFileFS(memFS, "x/y/a/b.txt"); err != nil || content != "b" {
+		test.Errorf("Expected content %q, got %q (%v)", "b", content, err)
+	}
+	if _, err := fs.Stat(memFS, "c.txt"); !errors.Is(err, fs.ErrNotExist) {
+		test.Errorf("Expected c.txt to be removed, got %v", err)
+	}
+	if fileInfo, err := fs.Stat(memFS, "x/y/z.txt"); err != nil || fileInfo.Mode().Perm() != 0600 {
+		test.Errorf("Expected mode 0600, got %v (%v)", fileInfo, err)
+	}
+}
+
+func TestCopyDirFSFromEmbedFS(test *testing.T) {
+	memFS := file.NewMemFS()
+
+	report, err := file.CopyDirFS(embeddedFS, "testdata/embedded", memFS, "out", file.CopyOptions{
+		Template: &file.TemplateOptions{Data: map[string]string{"Name": "World"}},
+	})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	if content, err := file.ReadFileFS(memFS, "out/hello.txt"); err != nil || content != "Hello World!\n" {
+		test.Errorf("Unexpected content %q (%v)", content, err)
+	}
+	if content, err := file.ReadFileFS(memFS, "out/sub/config.yaml"); err != nil || content != "name: old-name\n" {
+		test.Errorf("Unexpected content %q (%v)", content, err)
+	}
+	if len(report.Changes) != 4 {
+		test.Errorf("Expected 2 dirs and 2 files to be reported, got:\n%s", report)
+	}
+}
+
+func TestCopyDirFSBetweenOSAndMemFS(test *testing.T) {
+	srcDirPath := test.TempDir()
+	createTree(test, srcDirPath, map[string]string{"a.txt": "old value", "skip/b.txt": "b", "sub/c.txt": "old"})
+	memFS := file.NewMemFS()
+	options := file.CopyOptions{
+		Replacements:  map[string]string{"old": "new"},
+		FilterOptions: file.FilterOptions{Exclude: []string{"skip/"}},
+	}
+
+	if _, err := file.CopyDirFS(file.OSFS(srcDirPath), ".", memFS, "copy", options); err != nil {
+		test.Fatalf("Unexpected error copying to memory: %v", err)
+	}
+	dstDirPath := test.TempDir()
+	if _, err := file.CopyDirFS(memFS, "copy", file.OSFS(dstDirPath), ".", file.CopyOptions{}); err != nil {
+		test.Fatalf("Unexpected error copying to disk: %v", err)
+	}
+
+	expected := []string{"a.txt", "sub", "sub/c.txt"}
+	if tree := listTree(test, dstDirPath); !reflect.DeepEqual(tree, expected) {
+		test.Errorf("Expected tree %v, got %v", expected, tree)
+	}
+	if content := file.ReadFile(filepath.Join(dstDirPath, "a.txt")); content != "new value" {
+		test.Errorf("Unexpected content %q", content)
+	}
+}
+
+func TestCopyDirFSDryRun(test *testing.T) {
+	srcFS := fstest.MapFS{"a.txt": {Data: []byte("old\n"), Mode: 0644}}
+	memFS := file.NewMemFS()
+
+	report, err := file.CopyDirFS(srcFS, ".", memFS, "out",
+		file.CopyOptions{Replacements: map[string]string{"old": "new"}, DryRun: true})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err = fs.Stat(memFS, "out"); !errors.Is(err, fs.ErrNotExist) {
+		test.Errorf("Expected nothing to be written, got %v", err)
+	}
+	if len(report.Changes) != 2 || report.Changes[1].Diff == "" {
+		test.Errorf("Expected a dir and a file with diff, got:\n%s", report)
+	}
+}
+
+func TestRenameAndReplaceInFS(test *testing.T) {
+	memFS := file.NewMemFSWithFiles(map[string]string{"old/old.txt": "old content", "keep.txt": "old"})
+
+	report, err := file.RenameFilesInDirFS(memFS, ".", file.RenameOptions{Replacements: map[string]string{"old": "new"}})
+	if err != nil {
+		test.Fatalf("Unexpected error renaming: %v", err)
+	}
+	if len(report.Changes) != 2 {
+		test.Errorf("Expected two renames, got:\n%s", report)
+	}
+	count, err := file.ReplaceFileContentWithRulesFS(memFS, "new/new.txt", file.ReplaceRule{Pattern: `o(l)d`, Replacement: "g${1}d"})
+	if err != nil || count != 1 {
+		test.Fatalf("Expected one replacement, got %d (%v)", count, err)
+	}
+
+	if content, err := file.ReadFileFS(memFS, "new/new.txt"); err != nil || content != "gld content" {
+		test.Errorf("Unexpected content %q (%v)", content, err)
+	}
+}
+
+func TestReadOnlyFS(test *testing.T) {
+	readOnlyFS := file.ReadOnlyFS(embeddedFS)
+
+	lines, err := file.ReadFileLinesFS(readOnlyFS, "testdata/embedded/sub/config.yaml")
+	if err != nil || !reflect.DeepEqual(lines, []string{"name: old-name"}) {
+		test.Errorf("Unexpected lines %q (%v)", lines, err)
+	}
+	err = file.ReplaceFileContentFS(readOnlyFS, "testdata/embedded/sub/config.yaml", "old", "new")
+	if !errors.Is(err, file.ErrReadOnlyFS) {
+		test.Errorf("Expected ErrReadOnlyFS, got %v", err)
+	}
+}
+
+func TestCopyDirFSOptions(test *testing.T) {
+	srcFS := file.NewMemFSWithFiles(map[string]string{"a.txt": "a", "sub/b.txt": "b", "sub/c.txt": "c"})
+	if err := srcFS.Symlink("sub", "link"); err != nil {
+		test.Fatal(err)
+	}
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := srcFS.Chtimes("a.txt", time.Time{}, modTime); err != nil {
+		test.Fatal(err)
+	}
+	dstFS := file.NewMemFS()
+	var progress []file.CopyProgress
+	var mutex sync.Mutex
+
+	_, err := file.CopyDirFS(srcFS, ".", dstFS, "out", file.CopyOptions{
+		Symlinks:      file.SymlinksPreserve,
+		PreserveTimes: true,
+		Concurrency:   2,
+		Progress: func(p file.CopyProgress) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			progress = append(progress, p)
+		},
+	})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	if linkTarget, err := fs.ReadLink(dstFS, "out/link"); err != nil || linkTarget != "sub" {
+		test.Errorf("Expected the link to be preserved, got %q (%v)", linkTarget, err)
+	}
+	if fileInfo, err := fs.Stat(dstFS, "out/a.txt"); err != nil || !fileInfo.ModTime().Equal(modTime) {
+		test.Errorf("Expected modification time %v, got %v (%v)", modTime, fileInfo, err)
+	}
+	if len(progress) != 3 || progress[2].FilesDone != 3 || progress[2].FilesTotal != 3 {
+		test.Errorf("Expected progress for 3 files, got %+v", progress)
+	}
+}
+
+func TestCopyDirFSFollowsSymlinks(test *testing.T) {
+	srcFS := file.NewMemFSWithFiles(map[string]string{"sub/b.txt": "b"})
+	if err := srcFS.Symlink("sub", "link"); err != nil {
+		test.Fatal(err)
+	}
+	dstFS := file.NewMemFS()
+
+	if _, err := file.CopyDirFS(srcFS, ".", dstFS, ".", file.CopyOptions{}); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	if content, err := file.ReadFileFS(dstFS, "link/b.txt"); err != nil || content != "b" {
+		test.Errorf("Expected the linked dir to be copied, got %q (%v)", content, err)
+	}
+
+	if err := srcFS.Symlink("..", "sub/loop"); err != nil {
+		test.Fatal(err)
+	}
+	if _, err := file.CopyDirFS(srcFS, ".", file.NewMemFS(), ".", file.CopyOptions{}); !errors.Is(err, file.ErrSymlinkLoop) {
+		test.Errorf("Expected ErrSymlinkLoop, got %v", err)
+	}
+}
+
+func TestCopyDirFSUnsupportedOptions(test *testing.T) {
+	srcFS := file.NewMemFSWithFiles(map[string]string{"a.txt": "a"})
+	testCases := []struct {
+		name    string
+		dstFS   file.FS
+		options file.CopyOptions
+	}{
+		{name: "owner", dstFS: file.NewMemFS(), options: file.CopyOptions{PreserveOwner: true}},
+		{name: "xattrs", dstFS: file.OSFS(test.TempDir()), options: file.CopyOptions{PreserveXattrs: true}},
+		{name: "symlinks", dstFS: file.ReadOnlyFS(fstest.MapFS{}), options: file.CopyOptions{Symlinks: file.SymlinksPreserve}},
+		{name: "times", dstFS: file.ReadOnlyFS(fstest.MapFS{}), options: file.CopyOptions{PreserveTimes: true}},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			report, err := file.CopyDirFS(srcFS, ".", testCase.dstFS, ".", testCase.options)
+			if !errors.Is(err, errors.ErrUnsupported) {
+				t.Errorf("Expected errors.ErrUnsupported, got %v", err)
+			}
+			if len(report.Changes) != 0 {
+				t.Errorf("Expected nothing to be copied, got:\n%s", report)
+			}
+		})
+	}
+}
+
+func TestMemFSAdd(test *testing.T) {
+	memFS := file.NewMemFS()
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	memFS.Add("dir", nil, fs.ModeDir|0700, modTime)
+	memFS.Add("dir/run.sh", []byte("#!/bin/sh\n"), 0755, modTime)
+	memFS.Add("link", []byte("dir/run.sh"), fs.ModeSymlink|0777, modTime)
+
+	if fileInfo, err := fs.Stat(memFS, "dir"); err != nil || !fileInfo.IsDir() || fileInfo.Mode().Perm() != 0700 {
+		test.Errorf("Expected a dir with mode 0700, got %v (%v)", fileInfo, err)
+	}
+	if fileInfo, err := fs.Stat(memFS, "dir/run.sh"); err != nil || fileInfo.Mode() != 0755 || !fileInfo.ModTime().Equal(modTime) {
+		test.Errorf("Expected an executable file modified at %v, got %v (%v)", modTime, fileInfo, err)
+	}
+	if content, err := file.ReadFileFS(memFS, "link"); err != nil || content != "#!/bin/sh\n" {
+		test.Errorf("Expected to read through the link, got %q (%v)", content, err)
+	}
+}
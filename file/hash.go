@@ -0,0 +1,124 @@
+package file
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// HashOptions configures HashDir and DiffDirs
+type HashOptions struct {
+	// FilterOptions select the entries to look at, paths are matched relative to the hashed dir
+	FilterOptions
+	// IncludeModes takes the permission bits into account, otherwise only names, types and contents count
+	IncludeModes bool
+}
+
+// DirDiff lists the slash separated paths (relative to the compared dirs) which differ between two trees. A path which
+// changed its type, e.g. from file to dir, counts as modified.
+type DirDiff struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+// Empty tells if the trees are equal
+func (d *DirDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// HashDir computes a SHA-256 hash (hex encoded) over the dir's tree: the paths, types and contents of all entries, as
+// well as their modes if configured. The hash doesn't depend on the dir's own name or location, nor on modification
+// times, so two trees with equal content have equal hashes. Symlinks are hashed by their target, not followed.
+func HashDir(dirPath string, options HashOptions) (string, error) {
+	digests, err := digestTree(dirPath, options)
+	if err != nil {
+		return "", err
+	}
+	relPaths := make([]string, 0, len(digests))
+	for relPath := range digests {
+		relPaths = append(relPaths, relPath)
+	}
+	sort.Strings(relPaths)
+
+	hash := sha256.New()
+	for _, relPath := range relPaths {
+		// NUL can't be part of a path, so the records can't be confused with each other
+		fmt.Fprintf(hash, "%s\x00%s\n", relPath, digests[relPath])
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// DiffDirs compares the trees of two dirs, telling what was added, removed and modified from old to new
+func DiffDirs(oldDirPath, newDirPath string, options HashOptions) (*DirDiff, error) {
+	oldDigests, err := digestTree(oldDirPath, options)
+	if err != nil {
+		return nil, err
+	}
+	newDigests, err := digestTree(newDirPath, options)
+	if err != nil {
+		return nil, err
+	}
+
+	dirDiff := &DirDiff{}
+	for relPath, newDigest := range newDigests {
+		oldDigest, existing := oldDigests[relPath]
+		if !existing {
+			dirDiff.Added = append(dirDiff.Added, relPath)
+		} else if oldDigest != newDigest {
+			dirDiff.Modified = append(dirDiff.Modified, relPath)
+		}
+	}
+	for relPath := range oldDigests {
+		if _, existing := newDigests[relPath]; !existing {
+			dirDiff.Removed = append(dirDiff.Removed, relPath)
+		}
+	}
+	sort.Strings(dirDiff.Added)
+	sort.Strings(dirDiff.Removed)
+	sort.Strings(dirDiff.Modified)
+	return dirDiff, nil
+}
+
+// digestTree maps the relative paths of all selected entries to a digest of their type, content and optionally mode
+func digestTree(dirPath string, options HashOptions) (map[string]string, error) {
+	filter, err := NewFilter(options.FilterOptions)
+	if err != nil {
+		return nil, wrapError("hash dir", dirPath, err)
+	}
+	digests := make(map[string]string)
+	err = walkFiltered(dirPath, filter, func(entryPath, relPath string, dirEntry fs.DirEntry) error {
+		entryInfo, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		var digest string
+		switch {
+		case entryInfo.IsDir():
+			digest = "dir"
+		case entryInfo.Mode()&os.ModeSymlink != 0:
+			linkTarget, err := os.Readlink(entryPath)
+			if err != nil {
+				return err
+			}
+			digest = "link:" + linkTarget
+		case entryInfo.Mode().IsRegular():
+			contentHash, err := fileChecksum(entryPath, SHA256)
+			if err != nil {
+				return err
+			}
+			digest = "file:" + contentHash
+		default:
+			digest = "special:" + entryInfo.Mode().Type().String()
+		}
+		if options.IncludeModes {
+			digest += ":" + entryInfo.Mode().Perm().String()
+		}
+		digests[relPath] = digest
+		return nil
+	})
+	return digests, wrapError("hash dir", dirPath, err)
+}
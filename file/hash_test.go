@@ -0,0 +1,101 @@
+package file_test
+
+import (
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHashDir(test *testing.T) {
+	files := map[string]string{"a.txt": "a", "sub/b.txt": "b", "build/out.bin": "out"}
+
+	testCases := []struct {
+		name        string
+		change      func(t *testing.T, dirPath string)
+		options     file.HashOptions
+		expectEqual bool
+	}{
+		{
+			name:        "same content",
+			change:      func(t *testing.T, dirPath string) {},
+			expectEqual: true,
+		},
+		{
+			name:        "changed content",
+			change:      func(t *testing.T, dirPath string) { file.WriteFile(filepath.Join(dirPath, "a.txt"), "x", false) },
+			expectEqual: false,
+		},
+		{
+			name: "changed content in excluded dir",
+			change: func(t *testing.T, dirPath string) {
+				file.WriteFile(filepath.Join(dirPath, "build", "out.bin"), "x", false)
+			},
+			options:     file.HashOptions{FilterOptions: file.FilterOptions{Exclude: []string{"build/"}}},
+			expectEqual: true,
+		},
+		{
+			name: "renamed file",
+			change: func(t *testing.T, dirPath string) {
+				_ = os.Rename(filepath.Join(dirPath, "a.txt"), filepath.Join(dirPath, "c.txt"))
+			},
+			expectEqual: false,
+		},
+		{
+			name:        "changed mode ignored",
+			change:      func(t *testing.T, dirPath string) { _ = os.Chmod(filepath.Join(dirPath, "a.txt"), 0600) },
+			expectEqual: true,
+		},
+		{
+			name:        "changed mode included",
+			change:      func(t *testing.T, dirPath string) { _ = os.Chmod(filepath.Join(dirPath, "a.txt"), 0600) },
+			options:     file.HashOptions{IncludeModes: true},
+			expectEqual: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			oldDirPath, newDirPath := t.TempDir(), t.TempDir()
+			createTree(t, oldDirPath, files)
+			createTree(t, newDirPath, files)
+			testCase.change(t, newDirPath)
+
+			oldHash, err := file.HashDir(oldDirPath, testCase.options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			newHash, err := file.HashDir(newDirPath, testCase.options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if (oldHash == newHash) != testCase.expectEqual {
+				t.Errorf("Expected equal hashes: %v, got %s and %s", testCase.expectEqual, oldHash, newHash)
+			}
+		})
+	}
+}
+
+func TestDiffDirs(test *testing.T) {
+	oldDirPath, newDirPath := test.TempDir(), test.TempDir()
+	createTree(test, oldDirPath, map[string]string{"same.txt": "same", "changed.txt": "old", "removed.txt": "x", "dir/f": "f"})
+	createTree(test, newDirPath, map[string]string{"same.txt": "same", "changed.txt": "new", "added/new.txt": "x", "dir": "now a file"})
+
+	dirDiff, err := file.DiffDirs(oldDirPath, newDirPath, file.HashOptions{})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := &file.DirDiff{
+		Added:    []string{"added", "added/new.txt"},
+		Removed:  []string{"dir/f", "removed.txt"},
+		Modified: []string{"changed.txt", "dir"},
+	}
+	if !reflect.DeepEqual(dirDiff, expected) {
+		test.Errorf("Expected %+v but got %+v", expected, dirDiff)
+	}
+	if dirDiff.Empty() {
+		test.Errorf("Expected the diff not to be empty")
+	}
+}
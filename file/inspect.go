@@ -0,0 +1,162 @@
+package file
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PathKind is the type of a file system entry
+type PathKind string
+
+const (
+	PathFile    PathKind = "file"
+	PathDir     PathKind = "dir"
+	PathSymlink PathKind = "symlink"
+	// PathSpecial are FIFOs, sockets and device files
+	PathSpecial PathKind = "special"
+)
+
+// PathInfo describes a file system entry as returned by InspectPath
+type PathInfo struct {
+	Path    string
+	Kind    PathKind
+	Size    int64
+	Mode    fs.FileMode
+	ModTime time.Time
+	// LinkTarget is the target of a symlink as it is stored in the link
+	LinkTarget string
+	// UID and GID are -1 where the OS doesn't have them, Owner and Group are empty if the ids can't be looked up
+	UID   int
+	GID   int
+	Owner string
+	Group string
+}
+
+// InspectPath returns the details of a file system entry without following a symlink. A missing entry is reported as
+// an error wrapping fs.ErrNotExist.
+func InspectPath(path string) (*PathInfo, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, wrapError("inspect", path, err)
+	}
+	pathInfo := &PathInfo{Path: path, Kind: pathKind(info.Mode()), Size: info.Size(), Mode: info.Mode(),
+		ModTime: info.ModTime(), UID: -1, GID: -1}
+	if pathInfo.Kind == PathSymlink {
+		if pathInfo.LinkTarget, err = os.Readlink(path); err != nil {
+			return nil, wrapError("inspect", path, err)
+		}
+	}
+	if uid, gid, ok := fileOwner(info); ok {
+		pathInfo.UID, pathInfo.GID = uid, gid
+		if owner, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+			pathInfo.Owner = owner.Username
+		}
+		if group, err := user.LookupGroupId(strconv.Itoa(gid)); err == nil {
+			pathInfo.Group = group.Name
+		}
+	}
+	return pathInfo, nil
+}
+
+// ExistsE is Exists reporting errors other than the entry not existing (like missing permissions) instead of
+// assuming that the entry exists. Symlinks are followed, so a dangling link doesn't exist.
+func ExistsE(path string) (bool, error) {
+	_, err := statIfExists(path, os.Stat)
+	return err == nil, ignoreNotExist("exists", path, err)
+}
+
+// IsFile tells if the path is a regular file, following symlinks
+func IsFile(path string) (bool, error) {
+	info, err := statIfExists(path, os.Stat)
+	return err == nil && info.Mode().IsRegular(), ignoreNotExist("is file", path, err)
+}
+
+// IsDir tells if the path is a dir, following symlinks
+func IsDir(path string) (bool, error) {
+	info, err := statIfExists(path, os.Stat)
+	return err == nil && info.IsDir(), ignoreNotExist("is dir", path, err)
+}
+
+// IsSymlink tells if the path is a symlink, no matter if its target exists
+func IsSymlink(path string) (bool, error) {
+	info, err := statIfExists(path, os.Lstat)
+	return err == nil && info.Mode()&fs.ModeSymlink != 0, ignoreNotExist("is symlink", path, err)
+}
+
+// IsExecutable tells if the path is a regular file which can be executed, following symlinks: one of the execute bits
+// has to be set, or on Windows the file needs an executable extension (see PATHEXT)
+func IsExecutable(path string) (bool, error) {
+	info, err := statIfExists(path, os.Stat)
+	if err != nil || !info.Mode().IsRegular() {
+		return false, ignoreNotExist("is executable", path, err)
+	}
+	if runtime.GOOS == "windows" {
+		return hasExecutableExtension(path), nil
+	}
+	return info.Mode().Perm()&0111 != 0, nil
+}
+
+// IsEmptyDir tells if the path is a dir without any entries, following symlinks. A path which isn't a dir is reported
+// as an error.
+func IsEmptyDir(path string) (bool, error) {
+	dirRef, err := os.Open(path)
+	if err != nil {
+		return false, wrapError("is empty dir", path, err)
+	}
+	defer dirRef.Close()
+	if _, err = dirRef.Readdirnames(1); err == io.EOF {
+		return true, nil
+	}
+	return false, wrapError("is empty dir", path, err)
+}
+
+// statIfExists stats the path, err is fs.ErrNotExist itself for missing paths
+func statIfExists(path string, stat func(string) (os.FileInfo, error)) (os.FileInfo, error) {
+	info, err := stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fs.ErrNotExist
+	}
+	return info, err
+}
+
+// ignoreNotExist wraps all errors except fs.ErrNotExist, which is no error for the Is... functions
+func ignoreNotExist(op string, path string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return wrapError(op, path, err)
+}
+
+func pathKind(mode fs.FileMode) PathKind {
+	switch {
+	case mode.IsRegular():
+		return PathFile
+	case mode.IsDir():
+		return PathDir
+	case mode&fs.ModeSymlink != 0:
+		return PathSymlink
+	}
+	return PathSpecial
+}
+
+func hasExecutableExtension(path string) bool {
+	pathExt := os.Getenv("PATHEXT")
+	if pathExt == "" {
+		pathExt = ".com;.exe;.bat;.cmd"
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, executableExt := range strings.Split(strings.ToLower(pathExt), ";") {
+		if ext != "" && ext == executableExt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package file_test
+
+import (
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPathPredicates(test *testing.T) {
+	dirPath := test.TempDir()
+	createTree(test, dirPath, map[string]string{"file.txt": "content", "full/file.txt": "content"})
+	file.WriteFile(filepath.Join(dirPath, "run.sh"), "#!/bin/sh", true)
+	file.CreateDir(filepath.Join(dirPath, "empty"))
+	hasLinks := os.Symlink("file.txt", filepath.Join(dirPath, "link")) == nil &&
+		os.Symlink("missing", filepath.Join(dirPath, "dangling")) == nil
+
+	testCases := []struct {
+		name       string
+		path       string
+		exists     bool
+		file       bool
+		dir        bool
+		symlink    bool
+		executable bool
+		needsLinks bool
+	}{
+		{name: "file", path: "file.txt", exists: true, file: true},
+		{name: "executable", path: "run.sh", exists: true, file: true, executable: runtime.GOOS != "windows"},
+		{name: "dir", path: "full", exists: true, dir: true},
+		{name: "missing", path: "missing"},
+		{name: "link", path: "link", exists: true, file: true, symlink: true, needsLinks: true},
+		{name: "dangling link", path: "dangling", symlink: true, needsLinks: true},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			if testCase.needsLinks && !hasLinks {
+				t.Skip("Symlinks not supported")
+			}
+			path := filepath.Join(dirPath, testCase.path)
+			checks := []struct {
+				name      string
+				predicate func(string) (bool, error)
+				expected  bool
+			}{
+				{name: "ExistsE", predicate: file.ExistsE, expected: testCase.exists},
+				{name: "IsFile", predicate: file.IsFile, expected: testCase.file},
+				{name: "IsDir", predicate: file.IsDir, expected: testCase.dir},
+				{name: "IsSymlink", predicate: file.IsSymlink, expected: testCase.symlink},
+				{name: "IsExecutable", predicate: file.IsExecutable, expected: testCase.executable},
+			}
+			for _, check := range checks {
+				if result, err := check.predicate(path); err != nil || result != check.expected {
+					t.Errorf("%s: expected %v, got %v (%v)", check.name, check.expected, result, err)
+				}
+			}
+		})
+	}
+}
+
+func TestIsEmptyDir(test *testing.T) {
+	dirPath := test.TempDir()
+	createTree(test, dirPath, map[string]string{"full/file.txt": "content"})
+	file.CreateDir(filepath.Join(dirPath, "empty"))
+
+	if empty, err := file.IsEmptyDir(filepath.Join(dirPath, "empty")); err != nil || !empty {
+		test.Errorf("Expected empty dir, got %v (%v)", empty, err)
+	}
+	if empty, err := file.IsEmptyDir(filepath.Join(dirPath, "full")); err != nil || empty {
+		test.Errorf("Expected non-empty dir, got %v (%v)", empty, err)
+	}
+	if _, err := file.IsEmptyDir(filepath.Join(dirPath, "full", "file.txt")); err == nil {
+		test.Errorf("Expected an error for a file")
+	}
+	if _, err := file.IsEmptyDir(filepath.Join(dirPath, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		test.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestInspectPath(test *testing.T) {
+	dirPath := test.TempDir()
+	filePath := filepath.Join(dirPath, "file.txt")
+	file.WriteFile(filePath, "content", false)
+
+	pathInfo, err := file.InspectPath(filePath)
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	if pathInfo.Kind != file.PathFile || pathInfo.Size != 7 || !pathInfo.Mode.IsRegular() {
+		test.Errorf("Unexpected file info %+v", pathInfo)
+	}
+	if runtime.GOOS != "windows" && pathInfo.UID != os.Getuid() {
+		test.Errorf("Expected uid %d, got %+v", os.Getuid(), pathInfo)
+	}
+
+	if pathInfo, err = file.InspectPath(dirPath); err != nil || pathInfo.Kind != file.PathDir {
+		test.Errorf("Expected a dir, got %+v (%v)", pathInfo, err)
+	}
+	linkPath := filepath.Join(dirPath, "link")
+	if os.Symlink("file.txt", linkPath) == nil {
+		if pathInfo, err = file.InspectPath(linkPath); err != nil || pathInfo.Kind != file.PathSymlink ||
+			pathInfo.LinkTarget != "file.txt" {
+			test.Errorf("Expected a symlink to file.txt, got %+v (%v)", pathInfo, err)
+		}
+	}
+	if _, err = file.InspectPath(filepath.Join(dirPath, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		test.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+}
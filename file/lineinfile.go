@@ -0,0 +1,161 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// EnsureLinePresent appends the line to the file unless the file contains it already, creating the file if needed.
+// Lines are compared exactly, apart from a "\r" of "\r\n" line endings, which are also used for the appended line if
+// most of the file's lines have them. A symlinked file is changed at its target, keeping the link, rights and owner. Returns if the
+// file was changed.
+func EnsureLinePresent(filePath string, line string) (bool, error) {
+	return updateFileLines(filePath, "ensure line present", func(lines []string) ([]string, error) {
+		for _, existingLine := range lines {
+			if existingLine == line {
+				return lines, nil
+			}
+		}
+		return append(lines, line), nil
+	})
+}
+
+// EnsureLineAbsent removes all occurrences of the line from the file. A missing file is fine, as it doesn't contain
+// the line either. Returns if the file was changed.
+func EnsureLineAbsent(filePath string, line string) (bool, error) {
+	return updateFileLines(filePath, "ensure line absent", func(lines []string) ([]string, error) {
+		var keptLines []string
+		for _, existingLine := range lines {
+			if existingLine != line {
+				keptLines = append(keptLines, existingLine)
+			}
+		}
+		return keptLines, nil
+	})
+}
+
+// EnsureBlock makes the file contain the given content between the lines "# BEGIN name" and "# END name". An existing
+// block is replaced in place, otherwise the block is appended. Returns if the file was changed.
+func EnsureBlock(filePath string, name string, content string) (bool, error) {
+	return updateFileLines(filePath, "ensure block", func(lines []string) ([]string, error) {
+		block := []string{blockBegin(name)}
+		if content = strings.TrimSuffix(content, "\n"); content != "" {
+			block = append(block, strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")...)
+		}
+		block = append(block, blockEnd(name))
+
+		begin, end, err := findBlock(lines, name)
+		if err != nil || begin < 0 {
+			return append(lines, block...), err
+		}
+		return append(lines[:begin], append(block, lines[end+1:]...)...), nil
+	})
+}
+
+// EnsureBlockAbsent removes the block written by EnsureBlock including its marker lines. Returns if the file was
+// changed.
+func EnsureBlockAbsent(filePath string, name string) (bool, error) {
+	return updateFileLines(filePath, "ensure block absent", func(lines []string) ([]string, error) {
+		begin, end, err := findBlock(lines, name)
+		if err != nil || begin < 0 {
+			return lines, err
+		}
+		return append(lines[:begin], lines[end+1:]...), nil
+	})
+}
+
+func blockBegin(name string) string {
+	return "# BEGIN " + name
+}
+
+func blockEnd(name string) string {
+	return "# END " + name
+}
+
+// findBlock returns the line indexes of the block's markers, or -1 if there is no block
+func findBlock(lines []string, name string) (begin, end int, err error) {
+	begin, end = -1, -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case blockBegin(name):
+			if begin >= 0 {
+				return -1, -1, fmt.Errorf("duplicate block %q", name)
+			}
+			begin = i
+		case blockEnd(name):
+			if begin < 0 || end >= 0 {
+				return -1, -1, fmt.Errorf("unexpected end of block %q in line %d", name, i+1)
+			}
+			end = i
+		}
+	}
+	if begin >= 0 && end < 0 {
+		return -1, -1, fmt.Errorf("unterminated block %q", name)
+	}
+	return begin, end, nil
+}
+
+// updateFileLines applies update to the lines of the file (a missing file has none) and rewrites it atomically if
+// anything changed, keeping its line endings and rights. The lines passed to update don't contain the line endings.
+func updateFileLines(filePath string, op string, update func(lines []string) ([]string, error)) (bool, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, wrapError(op, filePath, err)
+	}
+	oldContent := string(content)
+	lines, lineEnds, lineEnd := splitLineEnds(oldContent)
+
+	lines, err = update(lines)
+	if err != nil {
+		return false, wrapError(op, filePath, err)
+	}
+	var newContent strings.Builder
+	for _, line := range lines {
+		newContent.WriteString(line)
+		if existingLineEnd, found := lineEnds[line]; found {
+			newContent.WriteString(existingLineEnd)
+		} else {
+			newContent.WriteString(lineEnd)
+		}
+	}
+	// A missing final line break alone isn't worth a rewrite
+	if newContent.String() == oldContent || newContent.String() == oldContent+lineEnd {
+		return false, nil
+	}
+	return true, wrapError(op, filePath, writeFileAtomicKeepingMode(filePath, []byte(newContent.String())))
+}
+
+// splitLineEnds splits content at "\n", dropping the "\r" of "\r\n" from the lines. Returns the line ending of each
+// distinct line, to be kept when writing it back, and the line ending most lines use, for added lines.
+func splitLineEnds(content string) ([]string, map[string]string, string) {
+	if content == "" {
+		return nil, nil, "\n"
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	lineEnds := make(map[string]string, len(lines))
+	crlfCount, lfCount := 0, 0
+	for index, line := range lines {
+		if index == len(lines)-1 && !strings.HasSuffix(content, "\n") {
+			// The line ending is added when writing the file again
+			break
+		}
+		lineEnd := "\n"
+		if strings.HasSuffix(line, "\r") {
+			lines[index] = strings.TrimSuffix(line, "\r")
+			lineEnd = "\r\n"
+			crlfCount++
+		} else {
+			lfCount++
+		}
+		if _, found := lineEnds[lines[index]]; !found {
+			lineEnds[lines[index]] = lineEnd
+		}
+	}
+	if crlfCount > lfCount {
+		return lines, lineEnds, "\r\n"
+	}
+	return lines, lineEnds, "\n"
+}
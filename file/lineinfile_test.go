@@ -0,0 +1,185 @@
+package file_test
+
+import (
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureLines(test *testing.T) {
+	testCases := []struct {
+		name            string
+		content         string
+		present         bool
+		expected        string
+		expectedChanged bool
+	}{
+		{name: "append", content: "a\nb\n", present: true, expected: "a\nb\nline\n", expectedChanged: true},
+		{name: "append without final line break", content: "a", present: true, expected: "a\nline\n", expectedChanged: true},
+		{name: "append crlf", content: "a\r\n", present: true, expected: "a\r\nline\r\n", expectedChanged: true},
+		{name: "present already", content: "a\nline\nb", present: true, expected: "a\nline\nb", expectedChanged: false},
+		{name: "present already crlf", content: "line\r\n", present: true, expected: "line\r\n", expectedChanged: false},
+		{name: "present already mixed", content: "a\nline\r\nb\n", present: true, expected: "a\nline\r\nb\n", expectedChanged: false},
+		{name: "append mixed", content: "a\r\nb\r\nc\n", present: true, expected: "a\r\nb\r\nc\nline\r\n", expectedChanged: true},
+		{name: "create file", content: "", present: true, expected: "line\n", expectedChanged: true},
+		{name: "remove all", content: "line\na\nline\n", present: false, expected: "a\n", expectedChanged: true},
+		{name: "remove mixed", content: "a\r\nline\r\nb\n", present: false, expected: "a\r\nb\n", expectedChanged: true},
+		{name: "remove absent", content: "a\nlines\n", present: false, expected: "a\nlines\n", expectedChanged: false},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "rc")
+			if testCase.content != "" {
+				file.WriteFile(filePath, testCase.content, false)
+			}
+
+			ensure := file.EnsureLineAbsent
+			if testCase.present {
+				ensure = file.EnsureLinePresent
+			}
+			changed, err := ensure(filePath, "line")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if changed != testCase.expectedChanged {
+				t.Errorf("Expected changed %v, got %v", testCase.expectedChanged, changed)
+			}
+			if content := file.ReadFile(filePath); content != testCase.expected {
+				t.Errorf("Expected %q, got %q", testCase.expected, content)
+			}
+
+			// A second run mustn't change anything
+			if changed, err = ensure(filePath, "line"); err != nil || changed {
+				t.Errorf("Expected second run to be a no-op, got changed %v, error %v", changed, err)
+			}
+		})
+	}
+}
+
+func TestEnsureLineAbsentMissingFile(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "missing")
+
+	changed, err := file.EnsureLineAbsent(filePath, "line")
+	if err != nil || changed {
+		test.Errorf("Expected no change, got changed %v, error %v", changed, err)
+	}
+	if _, err = os.Stat(filePath); err == nil {
+		test.Errorf("Expected file not to be created")
+	}
+}
+
+func TestEnsureBlock(test *testing.T) {
+	testCases := []struct {
+		name      string
+		content   string
+		block     string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "append",
+			content:  "127.0.0.1 localhost\n",
+			block:    "10.0.0.1 app\n10.0.0.2 db\n",
+			expected: "127.0.0.1 localhost\n# BEGIN hosts\n10.0.0.1 app\n10.0.0.2 db\n# END hosts\n",
+		},
+		{
+			name:     "replace in place",
+			content:  "a\n# BEGIN hosts\nold\n# END hosts\nb\n",
+			block:    "new",
+			expected: "a\n# BEGIN hosts\nnew\n# END hosts\nb\n",
+		},
+		{
+			name:     "other blocks untouched",
+			content:  "# BEGIN other\nx\n# END other\n",
+			block:    "new",
+			expected: "# BEGIN other\nx\n# END other\n# BEGIN hosts\nnew\n# END hosts\n",
+		},
+		{
+			name:     "crlf",
+			content:  "a\r\n# BEGIN hosts\r\nold\r\n# END hosts\r\n",
+			block:    "new\r\nnewer",
+			expected: "a\r\n# BEGIN hosts\r\nnew\r\nnewer\r\n# END hosts\r\n",
+		},
+		{name: "unterminated", content: "# BEGIN hosts\nold\n", block: "new", expectErr: true},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "hosts")
+			file.WriteFile(filePath, testCase.content, false)
+
+			_, err := file.EnsureBlock(filePath, "hosts", testCase.block)
+			if (err != nil) != testCase.expectErr {
+				t.Fatalf("Expected error: %v, got %v", testCase.expectErr, err)
+			}
+			if testCase.expectErr {
+				if content := file.ReadFile(filePath); content != testCase.content {
+					t.Errorf("Expected file to be untouched, got %q", content)
+				}
+				return
+			}
+			if content := file.ReadFile(filePath); content != testCase.expected {
+				t.Errorf("Expected %q, got %q", testCase.expected, content)
+			}
+			if changed, err := file.EnsureBlock(filePath, "hosts", testCase.block); err != nil || changed {
+				t.Errorf("Expected second run to be a no-op, got changed %v, error %v", changed, err)
+			}
+		})
+	}
+}
+
+func TestEnsureBlockAbsent(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "hosts")
+	file.WriteFile(filePath, "a\n# BEGIN hosts\nold\n# END hosts\nb\n", false)
+
+	changed, err := file.EnsureBlockAbsent(filePath, "hosts")
+	if err != nil || !changed {
+		test.Fatalf("Expected a change, got changed %v, error %v", changed, err)
+	}
+	if content := file.ReadFile(filePath); content != "a\nb\n" {
+		test.Errorf("Unexpected content %q", content)
+	}
+	if changed, err = file.EnsureBlockAbsent(filePath, "hosts"); err != nil || changed {
+		test.Errorf("Expected second run to be a no-op, got changed %v, error %v", changed, err)
+	}
+}
+
+func TestEnsureLinePresentSymlink(test *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{name: "existing target", content: "export A=1\n"},
+		{name: "dangling link"},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			homeDirPath := t.TempDir()
+			targetPath := filepath.Join(homeDirPath, "dotfiles", "bashrc")
+			file.CreateDir(filepath.Dir(targetPath))
+			if testCase.content != "" {
+				file.WriteFile(targetPath, testCase.content, false)
+			}
+			linkPath := filepath.Join(homeDirPath, ".bashrc")
+			if err := os.Symlink(filepath.Join("dotfiles", "bashrc"), linkPath); err != nil {
+				t.Skipf("Symlinks not supported: %v", err)
+			}
+
+			changed, err := file.EnsureLinePresent(linkPath, "export B=2")
+			if err != nil || !changed {
+				t.Fatalf("Expected a change without error but got %v, %v", changed, err)
+			}
+
+			if linkInfo, err := os.Lstat(linkPath); err != nil || linkInfo.Mode()&os.ModeSymlink == 0 {
+				t.Errorf("Expected .bashrc to stay a symlink but got %v, %v", linkInfo, err)
+			}
+			expected := testCase.content + "export B=2\n"
+			if content := file.ReadFile(targetPath); content != expected {
+				t.Errorf("Expected the link target to contain %q but got %q", expected, content)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package file_test
+
+import (
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestEnsureLinePresentKeepsOwner(test *testing.T) {
+	if os.Geteuid() != 0 {
+		test.Skip("Changing the owner needs root")
+	}
+	filePath := filepath.Join(test.TempDir(), "hosts")
+	file.WriteFile(filePath, "127.0.0.1 localhost\n", false)
+	if err := os.Chown(filePath, 1234, 5678); err != nil {
+		test.Fatal(err)
+	}
+
+	if _, err := file.EnsureLinePresent(filePath, "10.0.0.1 db"); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	stat := fileInfo.Sys().(*syscall.Stat_t)
+	if stat.Uid != 1234 || stat.Gid != 5678 || fileInfo.Mode().Perm() != 0644 {
+		test.Errorf("Expected owner 1234:5678 and mode 0644 but got %d:%d and %v", stat.Uid, stat.Gid, fileInfo.Mode())
+	}
+}
@@ -0,0 +1,102 @@
+package file
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"os"
+)
+
+// DefaultMaxLineLength is the line length limit used by the line readers if LineOptions doesn't set one
+const DefaultMaxLineLength = 1024 * 1024
+
+// LineOptions configures the line readers
+type LineOptions struct {
+	// MaxLineLength limits the length of a single line in bytes, protecting against files without line breaks. Zero
+	// means DefaultMaxLineLength, a negative value disables the limit.
+	MaxLineLength int
+}
+
+// Lines iterates over the lines read from reader without keeping more than the current line in memory. Lines end at
+// "\n" or "\r\n", the line ending isn't part of the line, and a final line break doesn't produce an empty last line.
+// A read error (including ErrLineTooLong) is yielded together with an empty line and ends the iteration.
+func Lines(reader io.Reader, options LineOptions) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		maxLineLength := options.maxLineLength()
+		// The scanner's limit includes the line ending, which may be "\r\n"
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 0, min(maxLineLength+2, 64*1024)), maxLineLength+2)
+		lineNumber := 0
+		for scanner.Scan() {
+			lineNumber++
+			if len(scanner.Bytes()) > maxLineLength {
+				yield("", fmt.Errorf("line %d: %w", lineNumber, ErrLineTooLong))
+				return
+			}
+			if !yield(scanner.Text(), nil) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			if errors.Is(err, bufio.ErrTooLong) {
+				err = fmt.Errorf("line %d: %w", lineNumber+1, ErrLineTooLong)
+			}
+			yield("", err)
+		}
+	}
+}
+
+// FileLines is Lines for a file, which is opened on the start of the iteration and closed at its end. Errors are
+// returned as OpError.
+func FileLines(filePath string, options LineOptions) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		fileRef, err := os.Open(filePath)
+		if err != nil {
+			yield("", wrapError("read file lines", filePath, err))
+			return
+		}
+		defer fileRef.Close()
+		for line, err := range Lines(fileRef, options) {
+			if !yield(line, wrapError("read file lines", filePath, err)) {
+				return
+			}
+		}
+	}
+}
+
+// ReadFileLineRange returns the lines from firstLine to lastLine (1-based, both inclusive) of a file, reading no
+// further than needed. A lastLine below 1 reads up to the end of the file. Files shorter than the range just return
+// less lines.
+func ReadFileLineRange(filePath string, firstLine, lastLine int, options LineOptions) ([]string, error) {
+	if firstLine < 1 || (lastLine >= 1 && lastLine < firstLine) {
+		return nil, wrapError("read file lines", filePath, fmt.Errorf("invalid line range %d-%d", firstLine, lastLine))
+	}
+	var lines []string
+	lineNumber := 0
+	for line, err := range FileLines(filePath, options) {
+		if err != nil {
+			return nil, err
+		}
+		lineNumber++
+		if lineNumber >= firstLine {
+			lines = append(lines, line)
+		}
+		if lineNumber == lastLine {
+			break
+		}
+	}
+	return lines, nil
+}
+
+// maxLineLength resolves the defaults of MaxLineLength, leaving room for the line ending in the unlimited case
+func (o LineOptions) maxLineLength() int {
+	if o.MaxLineLength == 0 {
+		return DefaultMaxLineLength
+	} else if o.MaxLineLength < 0 {
+		return math.MaxInt - 2
+	}
+	return min(o.MaxLineLength, math.MaxInt-2)
+}
@@ -0,0 +1,115 @@
+package file_test
+
+import (
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(test *testing.T) {
+	testCases := []struct {
+		name        string
+		content     string
+		maxLength   int
+		expected    []string
+		expectedErr error
+	}{
+		{name: "empty", content: "", expected: nil},
+		{name: "no final line break", content: "one\ntwo", expected: []string{"one", "two"}},
+		{name: "final line break", content: "one\ntwo\n", expected: []string{"one", "two"}},
+		{name: "crlf", content: "one\r\ntwo\r\n", expected: []string{"one", "two"}},
+		{name: "empty lines", content: "\n\none\n\n", expected: []string{"", "", "one", ""}},
+		{name: "at limit", content: "1234\r\n12\n", maxLength: 4, expected: []string{"1234", "12"}},
+		{name: "above limit", content: "12\n12345\n12", maxLength: 4, expected: []string{"12"}, expectedErr: file.ErrLineTooLong},
+		{name: "above limit without line break", content: "12345", maxLength: 4, expectedErr: file.ErrLineTooLong},
+		{name: "unlimited", content: strings.Repeat("x", 2*file.DefaultMaxLineLength), maxLength: -1,
+			expected: []string{strings.Repeat("x", 2*file.DefaultMaxLineLength)}},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			var lines []string
+			var lastErr error
+			for line, err := range file.Lines(strings.NewReader(testCase.content), file.LineOptions{MaxLineLength: testCase.maxLength}) {
+				if err != nil {
+					lastErr = err
+					continue
+				}
+				lines = append(lines, line)
+			}
+
+			if !errors.Is(lastErr, testCase.expectedErr) || (lastErr == nil) != (testCase.expectedErr == nil) {
+				t.Errorf("Expected error %v, got %v", testCase.expectedErr, lastErr)
+			}
+			if !reflect.DeepEqual(lines, testCase.expected) {
+				t.Errorf("Expected %q, got %q", testCase.expected, lines)
+			}
+		})
+	}
+}
+
+func TestFileLinesStopsEarly(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "lines.txt")
+	file.WriteFile(filePath, "one\ntwo\nthree\n", false)
+
+	var lines []string
+	for line, err := range file.FileLines(filePath, file.LineOptions{}) {
+		if err != nil {
+			test.Fatalf("Unexpected error: %v", err)
+		}
+		lines = append(lines, line)
+		if len(lines) == 2 {
+			break
+		}
+	}
+
+	if expected := []string{"one", "two"}; !reflect.DeepEqual(lines, expected) {
+		test.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestFileLinesMissingFile(test *testing.T) {
+	for _, err := range file.FileLines(filepath.Join(test.TempDir(), "missing.txt"), file.LineOptions{}) {
+		var opErr *file.OpError
+		if !errors.As(err, &opErr) || !errors.Is(err, fs.ErrNotExist) {
+			test.Errorf("Expected OpError wrapping fs.ErrNotExist, got %v", err)
+		}
+	}
+}
+
+func TestReadFileLineRange(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "lines.txt")
+	file.WriteFile(filePath, "1\r\n2\r\n3\r\n4\r\n5\r\n", false)
+
+	testCases := []struct {
+		name      string
+		firstLine int
+		lastLine  int
+		expected  []string
+		expectErr bool
+	}{
+		{name: "middle", firstLine: 2, lastLine: 4, expected: []string{"2", "3", "4"}},
+		{name: "single", firstLine: 3, lastLine: 3, expected: []string{"3"}},
+		{name: "to end", firstLine: 4, lastLine: 0, expected: []string{"4", "5"}},
+		{name: "beyond end", firstLine: 5, lastLine: 10, expected: []string{"5"}},
+		{name: "after end", firstLine: 6, lastLine: 10, expected: nil},
+		{name: "zero first line", firstLine: 0, lastLine: 2, expectErr: true},
+		{name: "reversed", firstLine: 3, lastLine: 2, expectErr: true},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			lines, err := file.ReadFileLineRange(filePath, testCase.firstLine, testCase.lastLine, file.LineOptions{})
+			if (err != nil) != testCase.expectErr {
+				t.Fatalf("Expected error: %v, got %v", testCase.expectErr, err)
+			}
+			if !reflect.DeepEqual(lines, testCase.expected) {
+				t.Errorf("Expected %q, got %q", testCase.expected, lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLocked is reported if a lock couldn't be acquired in time because another process holds it
+var ErrLocked = errors.New("locked by another process")
+
+// errLockTimeout is the cause of a context ended by the timeout of a lock, telling it apart from the caller's deadline
+var errLockTimeout = errors.New("timed out waiting for the lock")
+
+// lockPollInterval is the time waited between two attempts to get a lock
+const lockPollInterval = 50 * time.Millisecond
+
+// LockOptions configures LockFile
+type LockOptions struct {
+	// Shared locks can be held by several processes at once, e.g. for reading, while an exclusive lock (the default)
+	// excludes all other locks
+	Shared bool
+	// Timeout is the time to wait for the lock before giving up with ErrLocked, zero waits forever (or until the context
+	// is done)
+	Timeout time.Duration
+}
+
+// FileLock is an advisory lock (flock on Unix) held on a lock file. Advisory means that it only works between processes
+// using locks, it doesn't prevent anyone from writing to the file. Locks are released by the OS when the process dies.
+type FileLock struct {
+	path    string
+	fileRef *os.File
+}
+
+// LockFile locks the given lock file, creating it if needed - see LockFileContext
+func LockFile(lockPath string, options LockOptions) (*FileLock, error) {
+	return LockFileContext(context.Background(), lockPath, options)
+}
+
+// LockFileContext locks the given lock file, creating it if needed, and waits until the lock is acquired, the timeout
+// has passed (ErrLocked) or the context is done. The lock file can be the file to protect itself or a separate file
+// (which is left in place after unlocking, as deleting it would race with other processes waiting for it). Not
+// supported on non-Unix systems (errors.ErrUnsupported).
+func LockFileContext(ctx context.Context, lockPath string, options LockOptions) (*FileLock, error) {
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, options.Timeout, errLockTimeout)
+		defer cancel()
+	}
+	for {
+		lock, err := TryLockFile(lockPath, options)
+		if !errors.Is(err, ErrLocked) {
+			return lock, err
+		}
+		if err = waitForLock(ctx); err != nil {
+			return nil, wrapError("lock file", lockPath, err)
+		}
+	}
+}
+
+// TryLockFile locks the given lock file like LockFile, but fails with ErrLocked right away instead of waiting
+func TryLockFile(lockPath string, options LockOptions) (*FileLock, error) {
+	fileRef, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, wrapError("lock file", lockPath, err)
+	}
+	if err = tryFlock(fileRef, options.Shared); err != nil {
+		_ = fileRef.Close()
+		return nil, wrapError("lock file", lockPath, err)
+	}
+	return &FileLock{path: lockPath, fileRef: fileRef}, nil
+}
+
+// WithFileLock runs fn while holding the lock on the given lock file
+func WithFileLock(lockPath string, options LockOptions, fn func() error) (err error) {
+	lock, err := LockFile(lockPath, options)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := lock.Unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+	return fn()
+}
+
+// Path returns the path of the lock file
+func (l *FileLock) Path() string {
+	return l.path
+}
+
+// Unlock releases the lock, further calls do nothing
+func (l *FileLock) Unlock() error {
+	if l.fileRef == nil {
+		return nil
+	}
+	// Closing the file releases the lock
+	err := l.fileRef.Close()
+	l.fileRef = nil
+	return wrapError("unlock file", l.path, err)
+}
+
+// PIDLock is a lock file containing the PID of the process holding the lock. Unlike FileLock, it works on any
+// filesystem, but it has to be released explicitly: a lock file left behind by a dead process is detected as stale and
+// taken over. This only works for processes on the same host, and a PID reused by another process keeps the lock
+// alive.
+type PIDLock struct {
+	path string
+	pid  int
+}
+
+// AcquirePIDLock creates the lock file exclusively with the PID of the current process in it, waiting until the timeout
+// has passed (ErrLocked, zero waits forever) or the context is done while another living process holds it
+func AcquirePIDLock(ctx context.Context, lockPath string, timeout time.Duration) (*PIDLock, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, errLockTimeout)
+		defer cancel()
+	}
+	pid := os.Getpid()
+	for {
+		fileRef, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = fileRef.WriteString(strconv.Itoa(pid) + "\n")
+			if closeErr := fileRef.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				_ = os.Remove(lockPath)
+				return nil, wrapError("lock file", lockPath, err)
+			}
+			return &PIDLock{path: lockPath, pid: pid}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, wrapError("lock file", lockPath, err)
+		}
+		if removed, err := removeStalePIDLock(lockPath); err != nil {
+			return nil, wrapError("lock file", lockPath, err)
+		} else if removed {
+			continue
+		}
+		if err = waitForLock(ctx); err != nil {
+			return nil, wrapError("lock file", lockPath, err)
+		}
+	}
+}
+
+// Path returns the path of the lock file
+func (l *PIDLock) Path() string {
+	return l.path
+}
+
+// Release deletes the lock file, unless it has been taken over by another process in the meantime
+func (l *PIDLock) Release() error {
+	pid, err := readPIDLock(l.path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && pid != l.pid) {
+		return nil
+	}
+	if err != nil {
+		return wrapError("unlock file", l.path, err)
+	}
+	return wrapError("unlock file", l.path, os.Remove(l.path))
+}
+
+// removeStalePIDLock deletes the lock file if it is stale. The file is moved away before checking it again and
+// removing it, so that a lock created by another process in the meantime isn't removed instead of the stale one.
+func removeStalePIDLock(lockPath string) (bool, error) {
+	fileInfo, err := os.Stat(lockPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	if !stalePIDLock(lockPath, fileInfo) {
+		return false, nil
+	}
+
+	stalePath := fmt.Sprintf("%s.stale-%d-%d", lockPath, os.Getpid(), time.Now().UnixNano())
+	if err = os.Rename(lockPath, stalePath); errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	staleInfo, err := os.Stat(stalePath)
+	if err == nil && os.SameFile(fileInfo, staleInfo) && stalePIDLock(stalePath, staleInfo) {
+		return true, os.Remove(stalePath)
+	}
+
+	// Another process took over the lock in the meantime, it's put back unless yet another process holds it by now
+	if err = os.Link(stalePath, lockPath); err == nil || errors.Is(err, fs.ErrExist) {
+		return false, os.Remove(stalePath)
+	}
+	return false, os.Rename(stalePath, lockPath)
+}
+
+// stalePIDLock tells if the process named in the lock file doesn't exist anymore. A lock file without a valid PID is
+// treated as stale, too, unless it is brand-new, as its creator may not have written the PID yet.
+func stalePIDLock(lockPath string, fileInfo os.FileInfo) bool {
+	pid, err := readPIDLock(lockPath)
+	if err != nil {
+		return !errors.Is(err, fs.ErrNotExist) && time.Since(fileInfo.ModTime()) >= time.Second
+	}
+	return !processAlive(pid)
+}
+
+func readPIDLock(lockPath string) (int, error) {
+	content, err := os.ReadFile(lockPath)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid PID in lock file: %q", content)
+	}
+	return pid, nil
+}
+
+// waitForLock waits for the next attempt, translating the lock's own timeout to ErrLocked - the caller's deadline is
+// reported as context.DeadlineExceeded
+func waitForLock(ctx context.Context) error {
+	timer := time.NewTimer(lockPollInterval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		if errors.Is(context.Cause(ctx), errLockTimeout) {
+			return fmt.Errorf("%w: %w", ErrLocked, errLockTimeout)
+		}
+		return ctx.Err()
+	}
+}
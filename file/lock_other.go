@@ -0,0 +1,23 @@
+//go:build !unix
+
+package file
+
+import (
+	"errors"
+	"os"
+)
+
+// tryFlock is only implemented for Unix so far
+func tryFlock(fileRef *os.File, shared bool) error {
+	return errors.ErrUnsupported
+}
+
+// processAlive can't tell for sure without signals, so a process is taken as alive as long as it can be found
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = process.Release()
+	return true
+}
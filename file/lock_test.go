@@ -0,0 +1,102 @@
+package file_test
+
+import (
+	"context"
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPIDLock(test *testing.T) {
+	lockPath := filepath.Join(test.TempDir(), "tool.pid")
+
+	lock, err := file.AcquirePIDLock(context.Background(), lockPath, 0)
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	if content := file.ReadFile(lockPath); content != strconv.Itoa(os.Getpid())+"\n" {
+		test.Errorf("Expected the PID in the lock file, got %q", content)
+	}
+
+	// The current process is alive, so the lock isn't stale
+	if _, err = file.AcquirePIDLock(context.Background(), lockPath, 100*time.Millisecond); !errors.Is(err, file.ErrLocked) {
+		test.Errorf("Expected ErrLocked, got %v", err)
+	}
+
+	if err = lock.Release(); err != nil {
+		test.Fatalf("Unexpected error releasing: %v", err)
+	}
+	if file.Exists(lockPath) {
+		test.Errorf("Expected the lock file to be deleted")
+	}
+}
+
+func TestPIDLockStale(test *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		age     time.Duration
+	}{
+		{name: "dead process", content: "2147483600\n"},
+		{name: "garbage", content: "garbage", age: time.Minute},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			lockPath := filepath.Join(t.TempDir(), "tool.pid")
+			file.WriteFile(lockPath, testCase.content, false)
+			modTime := time.Now().Add(-testCase.age)
+			if err := os.Chtimes(lockPath, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+
+			lock, err := file.AcquirePIDLock(context.Background(), lockPath, time.Second)
+			if err != nil {
+				t.Fatalf("Expected the stale lock to be taken over, got %v", err)
+			}
+			defer lock.Release()
+			if content := file.ReadFile(lockPath); content != strconv.Itoa(os.Getpid())+"\n" {
+				t.Errorf("Expected the PID in the lock file, got %q", content)
+			}
+			if dirEntries, _ := os.ReadDir(filepath.Dir(lockPath)); len(dirEntries) != 1 {
+				t.Errorf("Expected only the lock file to be left but found %v", dirEntries)
+			}
+		})
+	}
+}
+
+func TestPIDLockReleaseAfterTakeover(test *testing.T) {
+	lockPath := filepath.Join(test.TempDir(), "tool.pid")
+	lock, err := file.AcquirePIDLock(context.Background(), lockPath, 0)
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	file.WriteFile(lockPath, "1\n", false)
+
+	if err = lock.Release(); err != nil {
+		test.Fatalf("Unexpected error releasing: %v", err)
+	}
+	if !file.Exists(lockPath) {
+		test.Errorf("Expected the lock file of the other process to be kept")
+	}
+}
+
+func TestPIDLockCallerDeadline(test *testing.T) {
+	lockPath := filepath.Join(test.TempDir(), "tool.pid")
+	lock, err := file.AcquirePIDLock(context.Background(), lockPath, 0)
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	defer lock.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = file.AcquirePIDLock(ctx, lockPath, time.Minute)
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, file.ErrLocked) {
+		test.Errorf("Expected context.DeadlineExceeded for the caller's deadline, got %v", err)
+	}
+}
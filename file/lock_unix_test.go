@@ -0,0 +1,114 @@
+//go:build unix
+
+package file_test
+
+import (
+	"context"
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTryLockFile(test *testing.T) {
+	testCases := []struct {
+		name         string
+		firstShared  bool
+		secondShared bool
+		expectLocked bool
+	}{
+		{name: "exclusive after exclusive", firstShared: false, secondShared: false, expectLocked: true},
+		{name: "shared after exclusive", firstShared: false, secondShared: true, expectLocked: true},
+		{name: "exclusive after shared", firstShared: true, secondShared: false, expectLocked: true},
+		{name: "shared after shared", firstShared: true, secondShared: true, expectLocked: false},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			lockPath := filepath.Join(t.TempDir(), "cache.lock")
+			firstLock, err := file.TryLockFile(lockPath, file.LockOptions{Shared: testCase.firstShared})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer firstLock.Unlock()
+
+			secondLock, err := file.TryLockFile(lockPath, file.LockOptions{Shared: testCase.secondShared})
+			if errors.Is(err, file.ErrLocked) != testCase.expectLocked {
+				t.Errorf("Expected locked: %v, got %v", testCase.expectLocked, err)
+			}
+			if err == nil {
+				_ = secondLock.Unlock()
+			}
+
+			if err = firstLock.Unlock(); err != nil {
+				t.Fatalf("Unexpected error unlocking: %v", err)
+			}
+			if secondLock, err = file.TryLockFile(lockPath, file.LockOptions{}); err != nil {
+				t.Errorf("Expected lock to be free after unlocking, got %v", err)
+			} else {
+				_ = secondLock.Unlock()
+			}
+		})
+	}
+}
+
+func TestLockFileTimeoutAndContext(test *testing.T) {
+	lockPath := filepath.Join(test.TempDir(), "cache.lock")
+	lock, err := file.LockFile(lockPath, file.LockOptions{})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	defer lock.Unlock()
+
+	if _, err = file.LockFile(lockPath, file.LockOptions{Timeout: 100 * time.Millisecond}); !errors.Is(err, file.ErrLocked) {
+		test.Errorf("Expected ErrLocked after timeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if _, err = file.LockFileContext(ctx, lockPath, file.LockOptions{}); !errors.Is(err, context.Canceled) {
+		test.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = file.LockFileContext(ctx, lockPath, file.LockOptions{Timeout: time.Minute})
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, file.ErrLocked) {
+		test.Errorf("Expected context.DeadlineExceeded for the caller's deadline, got %v", err)
+	}
+}
+
+func TestWithFileLockSerialises(test *testing.T) {
+	lockPath := filepath.Join(test.TempDir(), "cache.lock")
+	var active, maxActive int
+	var mutex sync.Mutex
+	var workers sync.WaitGroup
+
+	for range 5 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			err := file.WithFileLock(lockPath, file.LockOptions{Timeout: 10 * time.Second}, func() error {
+				mutex.Lock()
+				active++
+				maxActive = max(maxActive, active)
+				mutex.Unlock()
+				time.Sleep(20 * time.Millisecond)
+				mutex.Lock()
+				active--
+				mutex.Unlock()
+				return nil
+			})
+			if err != nil {
+				test.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	workers.Wait()
+
+	if maxActive != 1 {
+		test.Errorf("Expected one holder at a time, got %d", maxActive)
+	}
+}
@@ -0,0 +1,535 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemFS is an FS keeping everything in memory, e.g. for unit tests which shouldn't touch the disk. It is safe for
+// concurrent use and implements SymlinkFS, ChtimesFS, fs.ReadDirFS and fs.ReadLinkFS. Symlinks are followed within the
+// file system only, targets outside of it don't exist.
+type MemFS struct {
+	mutex sync.RWMutex
+	// entries holds all entries by name, including the root dir "." - the parent of every entry is a dir
+	entries map[string]*memEntry
+}
+
+// memEntry is a file, dir or symlink. Entries are replaced instead of changed, so that opened files keep what they
+// have, only the children of dirs are kept up-to-date in place.
+type memEntry struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+	// children holds the names of a dir's entries
+	children map[string]bool
+}
+
+// NewMemFS creates an empty MemFS
+func NewMemFS() *MemFS {
+	return &MemFS{entries: map[string]*memEntry{".": newMemDir(0755)}}
+}
+
+// NewMemFSWithFiles creates a MemFS with the given files (path -> content) and rights 0644
+func NewMemFSWithFiles(files map[string]string) *MemFS {
+	memFS := NewMemFS()
+	for name, content := range files {
+		memFS.Add(name, []byte(content), fileModeFor(false), time.Now())
+	}
+	return memFS
+}
+
+func newMemDir(perm fs.FileMode) *memEntry {
+	return &memEntry{mode: fs.ModeDir | perm.Perm(), modTime: time.Now(), children: make(map[string]bool)}
+}
+
+// Add puts an entry into the file system, replacing whatever is there and creating missing parent dirs with rights
+// 0755 - symlinks aren't followed. The mode tells the kind of entry: fs.ModeDir adds a dir (data is ignored),
+// fs.ModeSymlink a symlink with data as target, no type bits a file. Invalid names are ignored.
+func (f *MemFS) Add(name string, data []byte, mode fs.FileMode, modTime time.Time) {
+	if !fs.ValidPath(name) || name == "." {
+		return
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	dirName := path.Dir(name)
+	for _, parentName := range parentNames(dirName) {
+		if parent := f.entries[parentName]; parent == nil || !parent.mode.IsDir() {
+			f.removeLocked(parentName)
+			f.put(parentName, newMemDir(0755))
+		}
+	}
+	entry := &memEntry{data: slices.Clone(data), mode: mode, modTime: modTime}
+	if mode.IsDir() {
+		entry.data = nil
+		entry.children = make(map[string]bool)
+		if existing := f.entries[name]; existing != nil && existing.mode.IsDir() {
+			entry.children = existing.children
+		}
+	}
+	if !mode.IsDir() {
+		f.removeLocked(name)
+	}
+	f.put(name, entry)
+}
+
+// parentNames lists a dir and all its parents below the root, top-down
+func parentNames(dirName string) []string {
+	var names []string
+	for ; dirName != "."; dirName = path.Dir(dirName) {
+		names = append([]string{dirName}, names...)
+	}
+	return names
+}
+
+func (f *MemFS) Open(name string) (fs.File, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	resolvedName, entry, err := f.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	info := memFileInfo{name: path.Base(name), entry: entry}
+	if !entry.mode.IsDir() {
+		return &memFile{info: info, reader: bytes.NewReader(entry.data)}, nil
+	}
+	return &memDir{info: info, entries: f.dirEntries(resolvedName, entry)}, nil
+}
+
+func (f *MemFS) Stat(name string) (fs.FileInfo, error) {
+	return f.stat("stat", name, true)
+}
+
+func (f *MemFS) Lstat(name string) (fs.FileInfo, error) {
+	return f.stat("lstat", name, false)
+}
+
+func (f *MemFS) stat(op string, name string, followLink bool) (fs.FileInfo, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	_, entry, err := f.resolve(op, name, followLink)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return memFileInfo{name: path.Base(name), entry: entry}, nil
+}
+
+func (f *MemFS) ReadLink(name string) (string, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	_, entry, err := f.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return string(entry.data), nil
+}
+
+func (f *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	resolvedName, entry, err := f.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !entry.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return f.dirEntries(resolvedName, entry), nil
+}
+
+// dirEntries lists the entries of a dir sorted by name
+func (f *MemFS) dirEntries(dirName string, dir *memEntry) []fs.DirEntry {
+	var dirEntries []fs.DirEntry
+	for childName := range dir.children {
+		child := f.entries[path.Join(dirName, childName)]
+		dirEntries = append(dirEntries, fs.FileInfoToDirEntry(memFileInfo{name: childName, entry: child}))
+	}
+	slices.SortFunc(dirEntries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return dirEntries
+}
+
+func (f *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	dirName := "."
+	for _, element := range strings.Split(name, "/") {
+		if element == "." {
+			continue
+		}
+		resolvedName, entry, err := f.resolve("mkdir", path.Join(dirName, element), true)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			entry = newMemDir(perm)
+			f.put(resolvedName, entry)
+		}
+		if !entry.mode.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+		}
+		dirName = resolvedName
+	}
+	return nil
+}
+
+func (f *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return f.writeFile("write", name, data, perm)
+}
+
+// Create returns a writer whose content replaces the file's one when it is closed
+func (f *MemFS) Create(name string) (io.WriteCloser, error) {
+	if err := f.writeFile("open", name, nil, fileModeFor(false)); err != nil {
+		return nil, err
+	}
+	return &memFileWriter{memFS: f, name: name}, nil
+}
+
+func (f *MemFS) Chmod(name string, mode fs.FileMode) error {
+	return f.update("chmod", name, func(entry *memEntry) {
+		entry.mode = entry.mode&fs.ModeType | mode&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky)
+	})
+}
+
+// Chtimes only keeps the modification time, a zero time leaves it unchanged
+func (f *MemFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return f.update("chtimes", name, func(entry *memEntry) {
+		if !mtime.IsZero() {
+			entry.modTime = mtime
+		}
+	})
+}
+
+func (f *MemFS) Symlink(target, name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: fs.ErrInvalid}
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	resolvedName, entry, err := f.resolve("symlink", name, false)
+	if err != nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: fs.ErrNotExist}
+	}
+	if entry != nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: fs.ErrExist}
+	}
+	f.put(resolvedName, &memEntry{data: []byte(target), mode: fs.ModeSymlink | 0777, modTime: time.Now()})
+	return nil
+}
+
+// writeFile creates or truncates a file like os.WriteFile does, following symlinks
+func (f *MemFS) writeFile(op string, name string, data []byte, perm fs.FileMode) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	resolvedName, existing, err := f.resolve(op, name, true)
+	if err != nil {
+		return err
+	}
+	mode := perm.Perm()
+	if existing != nil {
+		if existing.mode.IsDir() {
+			return &fs.PathError{Op: op, Path: name, Err: errors.New("is a directory")}
+		}
+		// Like os.WriteFile, the rights of an existing file aren't changed
+		mode = existing.mode
+	}
+	f.put(resolvedName, &memEntry{data: slices.Clone(data), mode: mode, modTime: time.Now()})
+	return nil
+}
+
+// update replaces an existing entry by a changed copy, following symlinks like the OS does
+func (f *MemFS) update(op string, name string, change func(entry *memEntry)) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	resolvedName, existing, err := f.resolve(op, name, true)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	entry := *existing
+	change(&entry)
+	f.entries[resolvedName] = &entry
+	return nil
+}
+
+func (f *MemFS) Rename(oldName, newName string) error {
+	if !fs.ValidPath(oldName) || !fs.ValidPath(newName) || oldName == "." || newName == "." {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: fs.ErrInvalid}
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	// Like os.Rename, links in the names themselves aren't followed, only the ones in their parents
+	oldResolved, oldEntry, err := f.resolve("rename", oldName, false)
+	if err != nil || oldEntry == nil {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: fs.ErrNotExist}
+	}
+	newResolved, newEntry, err := f.resolve("rename", newName, false)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: fs.ErrNotExist}
+	}
+	if oldResolved == newResolved {
+		return nil
+	}
+	if oldEntry.mode.IsDir() && strings.HasPrefix(newResolved+"/", oldResolved+"/") {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: fs.ErrInvalid}
+	}
+	if newEntry != nil && newEntry.mode.IsDir() {
+		// Like os.Rename on Unix, only an empty dir can be replaced
+		if !oldEntry.mode.IsDir() || len(newEntry.children) > 0 {
+			return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: fs.ErrExist}
+		}
+	}
+	f.removeLocked(newResolved)
+
+	moved := make(map[string]*memEntry)
+	for name, entry := range f.entries {
+		if name == oldResolved || strings.HasPrefix(name, oldResolved+"/") {
+			moved[newResolved+strings.TrimPrefix(name, oldResolved)] = entry
+			delete(f.entries, name)
+		}
+	}
+	delete(f.entries[path.Dir(oldResolved)].children, path.Base(oldResolved))
+	for name, entry := range moved {
+		f.entries[name] = entry
+	}
+	f.entries[path.Dir(newResolved)].children[path.Base(newResolved)] = true
+	return nil
+}
+
+// RemoveAll doesn't follow a symlink, but removes it like os.RemoveAll does. Removing "." leaves an empty root.
+func (f *MemFS) RemoveAll(name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	resolvedName, entry, err := f.resolve("remove", name, false)
+	if err != nil || entry == nil {
+		// Like os.RemoveAll, a missing entry is fine
+		return nil
+	}
+	f.removeLocked(resolvedName)
+	return nil
+}
+
+// put stores an entry and registers it with its parent, which has to exist
+func (f *MemFS) put(name string, entry *memEntry) {
+	f.entries[name] = entry
+	if name != "." {
+		f.entries[path.Dir(name)].children[path.Base(name)] = true
+	}
+}
+
+// removeLocked removes an entry with everything below it
+func (f *MemFS) removeLocked(name string) {
+	entry := f.entries[name]
+	if entry == nil {
+		return
+	}
+	for childName := range entry.children {
+		f.removeLocked(path.Join(name, childName))
+	}
+	if name == "." {
+		return
+	}
+	delete(f.entries, name)
+	delete(f.entries[path.Dir(name)].children, path.Base(name))
+}
+
+// resolve follows the symlinks in all parents of a name, and in the name itself if followLink is set. Returns the
+// resolved name and its entry, which is nil if it doesn't exist (while its parent does). Links pointing out of the
+// file system don't resolve.
+func (f *MemFS) resolve(op string, name string, followLink bool) (string, *memEntry, error) {
+	if !fs.ValidPath(name) {
+		return "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	resolved := "."
+	remaining := strings.Split(name, "/")
+	for links := 0; len(remaining) > 0; {
+		element := remaining[0]
+		remaining = remaining[1:]
+		switch element {
+		case ".", "":
+			continue
+		case "..":
+			if resolved == "." {
+				return "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+			}
+			resolved = path.Dir(resolved)
+			continue
+		}
+		next := path.Join(resolved, element)
+		entry := f.entries[next]
+		if entry == nil {
+			if len(remaining) > 0 {
+				return "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+			}
+			return next, nil, nil
+		}
+		if entry.mode&fs.ModeSymlink == 0 || (len(remaining) == 0 && !followLink) {
+			if len(remaining) > 0 && !entry.mode.IsDir() {
+				return "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+			}
+			resolved = next
+			continue
+		}
+		// the limit of Linux
+		if links++; links > 40 {
+			return "", nil, &fs.PathError{Op: op, Path: name, Err: ErrSymlinkLoop}
+		}
+		linkTarget := string(entry.data)
+		if path.IsAbs(linkTarget) {
+			return "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		remaining = append(strings.Split(linkTarget, "/"), remaining...)
+	}
+	return resolved, f.entries[resolved], nil
+}
+
+// memFileInfo describes an entry under the name it has been looked up by
+type memFileInfo struct {
+	name  string
+	entry *memEntry
+}
+
+func (i memFileInfo) Name() string {
+	return i.name
+}
+
+func (i memFileInfo) Size() int64 {
+	return int64(len(i.entry.data))
+}
+
+func (i memFileInfo) Mode() fs.FileMode {
+	return i.entry.mode
+}
+
+func (i memFileInfo) ModTime() time.Time {
+	return i.entry.modTime
+}
+
+func (i memFileInfo) IsDir() bool {
+	return i.entry.mode.IsDir()
+}
+
+func (i memFileInfo) Sys() any {
+	return nil
+}
+
+// memFile is an opened file, reading the content it had when it was opened
+type memFile struct {
+	info   memFileInfo
+	reader *bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	return f.reader.Read(p)
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	return f.reader.Seek(offset, whence)
+}
+
+func (f *memFile) ReadAt(p []byte, offset int64) (int, error) {
+	return f.reader.ReadAt(p, offset)
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+// memDir is an opened dir, listing the entries it had when it was opened
+type memDir struct {
+	info    memFileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *memDir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *memDir) Close() error {
+	return nil
+}
+
+// ReadDir works like fs.ReadDirFile describes: with n > 0, at most n entries are returned and io.EOF at the end
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return slices.Clone(remaining), nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	remaining = remaining[:min(n, len(remaining))]
+	d.offset += len(remaining)
+	return slices.Clone(remaining), nil
+}
+
+// memFileWriter collects the content written to a file created by MemFS.Create
+type memFileWriter struct {
+	memFS  *MemFS
+	name   string
+	buffer bytes.Buffer
+	closed bool
+}
+
+func (w *memFileWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, &fs.PathError{Op: "write", Path: w.name, Err: fs.ErrClosed}
+	}
+	return w.buffer.Write(p)
+}
+
+// Close stores the content, further calls do nothing
+func (w *memFileWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.memFS.writeFile("write", w.name, w.buffer.Bytes(), fileModeFor(false))
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package file
+
+import "os"
+
+// fileOwner can't tell without uid and gid
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	return -1, -1, false
+}
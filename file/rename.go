@@ -0,0 +1,352 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrRenameCollision is reported if renaming would make two entries end up with the same name
+var ErrRenameCollision = errors.New("rename collision")
+
+// RenameRule replaces all occurrences of Old by New in a file or dir name
+type RenameRule struct {
+	Old string
+	New string
+}
+
+// RenameOptions configures RenameFilesInDirWithOptions
+type RenameOptions struct {
+	// Rules are applied one after another, so a rule sees the name as changed by the rules before
+	Rules []RenameRule
+	// Replacements are done after the rules, all in a single pass like CopyFileAndReplaceContent does, so that
+	// swapping names works
+	Replacements map[string]string
+	// FilterOptions select the entries to rename, paths are matched relative to the given dir. With include patterns,
+	// dirs are only renamed if they match one of them.
+	FilterOptions
+	// DryRun doesn't rename anything but only plans what would be done, see RenameFilesInDirWithReport
+	DryRun bool
+}
+
+// CopyDirAndRenameFilesWithOptions copies a whole directory as configured by the given options and then renames the
+// copied files as RenameFilesInDir does, applying the same filter to the renaming
+func CopyDirAndRenameFilesWithOptions(srcDirPath, dstDirPath string, replacements map[string]string,
+	options CopyOptions) error {
+	_, err := CopyDirAndRenameFilesWithReport(srcDirPath, dstDirPath, replacements, options)
+	return err
+}
+
+// CopyDirAndRenameFilesWithReport is CopyDirAndRenameFilesWithOptions reporting all changes. In a dry run, the renames
+// are planned on top of the planned copy.
+func CopyDirAndRenameFilesWithReport(srcDirPath, dstDirPath string, replacements map[string]string,
+	options CopyOptions) (*Report, error) {
+	report, err := CopyDirWithReport(srcDirPath, dstDirPath, options)
+	if err != nil {
+		return report, err
+	}
+
+	renamer, err := newDirRenamer(OSFS(dstDirPath), ".", RenameOptions{
+		Replacements:  replacements,
+		FilterOptions: options.FilterOptions,
+		DryRun:        options.DryRun,
+	})
+	if err != nil {
+		return report, err
+	}
+	entries, err := renamer.listEntries()
+	if err != nil {
+		return report, err
+	}
+	if options.DryRun {
+		entries = renamer.addPlannedEntries(entries, report)
+	}
+	renameReport, err := renamer.rename(entries)
+	report.Changes = append(report.Changes, renameReport.Changes...)
+	return report, err
+}
+
+// RenameFilesInDirWithOptions renames the files and dirs in the given directory as configured by the given options
+func RenameFilesInDirWithOptions(dirPath string, options RenameOptions) error {
+	_, err := RenameFilesInDirWithReport(dirPath, options)
+	return err
+}
+
+// RenameFilesInDirWithReport renames the files and dirs in the given directory as configured by the given options and
+// reports the renames. With DryRun set, nothing is renamed and the report is the plan of what would be done.
+func RenameFilesInDirWithReport(dirPath string, options RenameOptions) (*Report, error) {
+	return RenameFilesInDirFS(OSFS(dirPath), ".", options)
+}
+
+type dirRenamer struct {
+	fsys FS
+	// dirName is the name of the dir within fsys
+	dirName string
+	options RenameOptions
+	filter  *Filter
+}
+
+// renameEntry is a file or dir below the renamed dir
+type renameEntry struct {
+	relPath string
+	isDir   bool
+}
+
+// renameStep is a planned rename with the names within the file system, the change has the paths to report
+type renameStep struct {
+	change  Change
+	oldName string
+	newName string
+}
+
+func newDirRenamer(fsys FS, dirName string, options RenameOptions) (*dirRenamer, error) {
+	filter, err := NewFilter(options.FilterOptions)
+	if err != nil {
+		return nil, wrapError("rename", fsPath(fsys, dirName), err)
+	}
+	return &dirRenamer{fsys: fsys, dirName: dirName, options: options, filter: filter}, nil
+}
+
+// listEntries collects the entries of the dir to be renamed. In a dry run, a missing dir has no entries yet.
+func (r *dirRenamer) listEntries() ([]renameEntry, error) {
+	var entries []renameEntry
+	collect := func(entryPath, relPath string, dirEntry fs.DirEntry) error {
+		entries = append(entries, renameEntry{relPath: relPath, isDir: dirEntry.IsDir()})
+		return nil
+	}
+	err := walkFilteredFS(r.fsys, r.dirName, r.filter, collect)
+	if r.options.DryRun && errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return entries, wrapError("rename", fsPath(r.fsys, r.dirName), err)
+}
+
+// addPlannedEntries adds the entries a planned copy would create in the dir, which of course can't be listed yet
+func (r *dirRenamer) addPlannedEntries(entries []renameEntry, report *Report) []renameEntry {
+	known := make(map[string]bool)
+	for _, entry := range entries {
+		known[entry.relPath] = true
+	}
+	for _, change := range report.Changes {
+		relPath, err := filepath.Rel(fsPath(r.fsys, r.dirName), change.Path)
+		if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+			continue
+		}
+		relPath = filepath.ToSlash(relPath)
+		if !known[relPath] && r.filter.selectsEntry(relPath, change.IsDir) {
+			known[relPath] = true
+			entries = append(entries, renameEntry{relPath: relPath, isDir: change.IsDir})
+		}
+	}
+	return entries
+}
+
+// rename plans the renames of the given entries and executes them unless it's a dry run. Nothing is renamed if the
+// plan contains collisions, errors of single renames don't stop the others. All errors are joined.
+func (r *dirRenamer) rename(entries []renameEntry) (*Report, error) {
+	report := &Report{DryRun: r.options.DryRun}
+	steps, err := r.plan(entries)
+	if r.options.DryRun {
+		for _, step := range steps {
+			report.Changes = append(report.Changes, step.change)
+		}
+		return report, err
+	}
+	if err != nil {
+		return report, err
+	}
+
+	var errs []error
+	for _, step := range steps {
+		if err = r.fsys.Rename(step.oldName, step.newName); err != nil {
+			errs = append(errs, wrapError("rename", step.change.Source, err))
+			continue
+		}
+		report.add(step.change)
+	}
+	return report, errors.Join(errs...)
+}
+
+// newName applies the rules one after another, followed by the replacements
+func (r *dirRenamer) newName(name string, replacer *strings.Replacer) string {
+	for _, rule := range r.options.Rules {
+		if rule.Old != "" {
+			name = strings.ReplaceAll(name, rule.Old, rule.New)
+		}
+	}
+	return replacer.Replace(name)
+}
+
+// newNameReplacer creates a replacer for the replacements, which are ordered like streamReplacer does
+func (r *dirRenamer) newNameReplacer() *strings.Replacer {
+	var oldNew []string
+	streamReplacer := newStreamReplacer(r.options.Replacements)
+	for i := range streamReplacer.oldValues {
+		oldNew = append(oldNew, string(streamReplacer.oldValues[i]), string(streamReplacer.newValues[i]))
+	}
+	return strings.NewReplacer(oldNew...)
+}
+
+// plan works bottom-up: entries in deeper dirs are renamed first, so that renaming a dir never invalidates the paths
+// of renames still to be done
+func (r *dirRenamer) plan(entries []renameEntry) ([]renameStep, error) {
+	replacer := r.newNameReplacer()
+	known := make(map[string]bool)
+	entriesByDir := make(map[string][]renameEntry)
+	for _, entry := range entries {
+		known[entry.relPath] = true
+		dirRelPath := path.Dir(entry.relPath)
+		entriesByDir[dirRelPath] = append(entriesByDir[dirRelPath], entry)
+	}
+	var dirRelPaths []string
+	for dirRelPath := range entriesByDir {
+		dirRelPaths = append(dirRelPaths, dirRelPath)
+	}
+	sort.Slice(dirRelPaths, func(i, j int) bool {
+		iDepth, jDepth := pathDepth(dirRelPaths[i]), pathDepth(dirRelPaths[j])
+		if iDepth != jDepth {
+			return iDepth > jDepth
+		}
+		return dirRelPaths[i] < dirRelPaths[j]
+	})
+
+	var steps []renameStep
+	var errs []error
+	for _, dirRelPath := range dirRelPaths {
+		dirSteps, err := r.planDir(dirRelPath, entriesByDir[dirRelPath], replacer, known)
+		steps = append(steps, dirSteps...)
+		errs = append(errs, err)
+	}
+	return steps, errors.Join(errs...)
+}
+
+// pathDepth returns the number of dirs in a slash separated relative path, "." being the root above everything
+func pathDepth(relPath string) int {
+	if relPath == "." {
+		return -1
+	}
+	return strings.Count(relPath, "/")
+}
+
+// renameMove renames an entry within its dir
+type renameMove struct {
+	oldName string
+	newName string
+	isDir   bool
+}
+
+// planDir plans the renames within a single dir. Moves whose new name is taken by an entry staying where it is, or
+// which end up with the same name as another move, are collisions. Chains like a->b, b->c are ordered so that b is
+// moved away first, cycles like a->b, b->a are broken up by moving one entry to a temporary name.
+func (r *dirRenamer) planDir(dirRelPath string, entries []renameEntry, replacer *strings.Replacer,
+	known map[string]bool) ([]renameStep, error) {
+	var errs []error
+	var moves []renameMove
+	for _, entry := range entries {
+		oldName := path.Base(entry.relPath)
+		newName := r.newName(oldName, replacer)
+		if newName == oldName {
+			continue
+		}
+		if newName == "" || newName == "." || newName == ".." || strings.ContainsAny(newName, `/\`) {
+			errs = append(errs, wrapError("rename", r.reportPath(entry.relPath), fmt.Errorf("invalid new name '%s'", newName)))
+			continue
+		}
+		moves = append(moves, renameMove{oldName: oldName, newName: newName, isDir: entry.isDir})
+	}
+
+	// Dropping a colliding move lets its entry stay, which might make another move collide - so repeat until stable
+	for collisionFound := true; collisionFound; {
+		collisionFound = false
+		movingNames := make(map[string]bool)
+		targetCounts := make(map[string]int)
+		for _, move := range moves {
+			movingNames[move.oldName] = true
+			targetCounts[move.newName]++
+		}
+		var validMoves []renameMove
+		for _, move := range moves {
+			var collision string
+			if targetCounts[move.newName] > 1 {
+				collision = "another entry renamed to the same name"
+			} else if !movingNames[move.newName] && r.exists(path.Join(dirRelPath, move.newName), known) {
+				collision = "existing entry " + r.reportPath(path.Join(dirRelPath, move.newName))
+			}
+			if collision != "" {
+				err := fmt.Errorf("%w: '%s' clashes with %s", ErrRenameCollision, move.newName, collision)
+				errs = append(errs, wrapError("rename", r.reportPath(path.Join(dirRelPath, move.oldName)), err))
+				collisionFound = true
+				continue
+			}
+			validMoves = append(validMoves, move)
+		}
+		moves = validMoves
+	}
+
+	var steps []renameStep
+	newStep := func(oldName, newName string, isDir bool) renameStep {
+		step := renameStep{oldName: r.name(path.Join(dirRelPath, oldName)), newName: r.name(path.Join(dirRelPath, newName))}
+		step.change = Change{
+			Kind:   ChangeRename,
+			Path:   fsPath(r.fsys, step.newName),
+			Source: fsPath(r.fsys, step.oldName),
+			IsDir:  isDir,
+		}
+		return step
+	}
+	for len(moves) > 0 {
+		movingNames := make(map[string]bool)
+		for _, move := range moves {
+			movingNames[move.oldName] = true
+		}
+		progressed := false
+		for i, move := range moves {
+			if !movingNames[move.newName] {
+				steps = append(steps, newStep(move.oldName, move.newName, move.isDir))
+				moves = append(moves[:i], moves[i+1:]...)
+				progressed = true
+				break
+			}
+		}
+		if !progressed {
+			// All remaining moves wait for each other, so at least one cycle is left
+			tempName := r.tempName(dirRelPath, moves[0].oldName, known)
+			steps = append(steps, newStep(moves[0].oldName, tempName, moves[0].isDir))
+			moves[0].oldName = tempName
+		}
+	}
+	return steps, errors.Join(errs...)
+}
+
+// exists checks if an entry exists on disk or is known from a planned copy
+func (r *dirRenamer) exists(relPath string, known map[string]bool) bool {
+	if known[relPath] {
+		return true
+	}
+	_, err := fs.Lstat(r.fsys, r.name(relPath))
+	return err == nil
+}
+
+// tempName finds a free name for parking an entry while breaking up a rename cycle
+func (r *dirRenamer) tempName(dirRelPath, name string, known map[string]bool) string {
+	for i := 0; ; i++ {
+		tempName := fmt.Sprintf(".%s.rename-%d", name, i)
+		if !r.exists(path.Join(dirRelPath, tempName), known) {
+			return tempName
+		}
+	}
+}
+
+// name returns the name within the file system of an entry below the renamed dir
+func (r *dirRenamer) name(relPath string) string {
+	return path.Join(r.dirName, relPath)
+}
+
+// reportPath returns the path of an entry below the renamed dir to report
+func (r *dirRenamer) reportPath(relPath string) string {
+	return fsPath(r.fsys, r.name(relPath))
+}
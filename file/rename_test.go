@@ -0,0 +1,125 @@
+package file_test
+
+import (
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRenameFilesInDirWithOptions(test *testing.T) {
+	testCases := []struct {
+		name     string
+		files    map[string]string
+		options  file.RenameOptions
+		expected []string
+	}{
+		{
+			name:     "all occurrences",
+			files:    map[string]string{"app/app_app.go": "app"},
+			options:  file.RenameOptions{Replacements: map[string]string{"app": "svc"}},
+			expected: []string{"svc", "svc/svc_svc.go"},
+		},
+		{
+			name:     "nested dirs",
+			files:    map[string]string{"app/app/app/app.txt": "app"},
+			options:  file.RenameOptions{Replacements: map[string]string{"app": "svc"}},
+			expected: []string{"svc", "svc/svc", "svc/svc/svc", "svc/svc/svc/svc.txt"},
+		},
+		{
+			name:     "longest replacement first",
+			files:    map[string]string{"app_name.txt": "", "app.txt": ""},
+			options:  file.RenameOptions{Replacements: map[string]string{"app": "svc", "app_name": "title"}},
+			expected: []string{"svc.txt", "title.txt"},
+		},
+		{
+			name:     "ordered rules",
+			files:    map[string]string{"a.txt": ""},
+			options:  file.RenameOptions{Rules: []file.RenameRule{{Old: "a", New: "b"}, {Old: "b", New: "c"}}},
+			expected: []string{"c.txt"},
+		},
+		{
+			name:     "chain",
+			files:    map[string]string{"a1": "", "a2": ""},
+			options:  file.RenameOptions{Rules: []file.RenameRule{{Old: "2", New: "3"}, {Old: "1", New: "2"}}},
+			expected: []string{"a2", "a3"},
+		},
+		{
+			name:     "cycle",
+			files:    map[string]string{"blue": "blue", "green": "green"},
+			options:  file.RenameOptions{Replacements: map[string]string{"blue": "green", "green": "blue"}},
+			expected: []string{"blue", "green"},
+		},
+		{
+			name:     "filter",
+			files:    map[string]string{"app.go": "", "vendor/app.go": ""},
+			options:  file.RenameOptions{Replacements: map[string]string{"app": "svc"}, FilterOptions: file.FilterOptions{Exclude: []string{"vendor/"}}},
+			expected: []string{"svc.go", "vendor", "vendor/app.go"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			dirPath := t.TempDir()
+			createTree(t, dirPath, testCase.files)
+
+			if err := file.RenameFilesInDirWithOptions(dirPath, testCase.options); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result := listTree(t, dirPath); !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("Expected %v but got %v", testCase.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenameFilesInDirCycleKeepsContent(test *testing.T) {
+	dirPath := test.TempDir()
+	createTree(test, dirPath, map[string]string{"blue": "blue", "green": "green"})
+
+	if err := file.RenameFilesInDir(dirPath, map[string]string{"blue": "green", "green": "blue"}); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	if file.ReadFile(filepath.Join(dirPath, "blue")) != "green" || file.ReadFile(filepath.Join(dirPath, "green")) != "blue" {
+		test.Errorf("Expected the files to be swapped")
+	}
+}
+
+func TestRenameFilesInDirCollisions(test *testing.T) {
+	testCases := []struct {
+		name  string
+		files map[string]string
+		rules []file.RenameRule
+	}{
+		{
+			name:  "existing entry",
+			files: map[string]string{"a.txt": "", "b.txt": ""},
+			rules: []file.RenameRule{{Old: "a", New: "b"}},
+		},
+		{
+			name:  "same new name",
+			files: map[string]string{"a1.txt": "", "a2.txt": ""},
+			rules: []file.RenameRule{{Old: "1", New: ""}, {Old: "2", New: ""}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			dirPath := t.TempDir()
+			createTree(t, dirPath, testCase.files)
+			treeBefore := listTree(t, dirPath)
+
+			err := file.RenameFilesInDirWithOptions(dirPath, file.RenameOptions{Rules: testCase.rules})
+
+			if !errors.Is(err, file.ErrRenameCollision) {
+				t.Errorf("Expected ErrRenameCollision but got %v", err)
+			}
+			if result := listTree(t, dirPath); !reflect.DeepEqual(result, treeBefore) {
+				t.Errorf("Expected nothing to be renamed but got %v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"sort"
+)
+
+// sniffLen is the number of bytes looked at to tell text from binary content
+const sniffLen = 512
+
+// IsBinaryFile tells if the file's content looks binary (see isBinaryContent)
+func IsBinaryFile(filePath string) (bool, error) {
+	fileRef, err := os.Open(filePath)
+	if err != nil {
+		return false, wrapError("sniff file", filePath, err)
+	}
+	defer fileRef.Close()
+
+	sample := make([]byte, sniffLen)
+	sampleLen, err := io.ReadFull(fileRef, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, wrapError("sniff file", filePath, err)
+	}
+	return isBinaryContent(sample[:sampleLen]), nil
+}
+
+// isBinaryContent sniffs the start of some content the way git does: NUL bytes never appear in (UTF-8) text files,
+// and control characters other than the ones used in text (like tabs, line breaks or the escape of terminal colors)
+// are rare in them - more than one in 128 bytes means binary. The content itself doesn't matter, so text which happens
+// to start like some file format (e.g. "BM" like a bitmap) is still text.
+func isBinaryContent(sample []byte) bool {
+	printable, nonPrintable := 0, 0
+	for _, b := range sample {
+		switch {
+		case b == 0:
+			return true
+		case b == 0x7f:
+			nonPrintable++
+		case b >= 0x20, b == '\b', b == '\t', b == '\n', b == '\f', b == '\r', b == 0x1b:
+			printable++
+		default:
+			nonPrintable++
+		}
+	}
+	return printable>>7 < nonPrintable
+}
+
+// streamReplacer replaces several literal values within one pass over a stream. Overlapping keys are resolved
+// deterministically: at each position the longest matching key wins, keys of the same length are tried in lexical
+// order. Replaced text is not looked at again, so one replacement never feeds into another.
+type streamReplacer struct {
+	oldValues [][]byte
+	newValues [][]byte
+	// candidates maps the first byte of the keys to the indexes of all keys starting with it
+	candidates [256][]int
+	maxOldLen  int
+}
+
+func newStreamReplacer(replacements map[string]string) *streamReplacer {
+	var keys []string
+	for key := range replacements {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	replacer := &streamReplacer{}
+	for i, key := range keys {
+		replacer.oldValues = append(replacer.oldValues, []byte(key))
+		replacer.newValues = append(replacer.newValues, []byte(replacements[key]))
+		replacer.candidates[key[0]] = append(replacer.candidates[key[0]], i)
+		replacer.maxOldLen = max(replacer.maxOldLen, len(key))
+	}
+	return replacer
+}
+
+func (r *streamReplacer) empty() bool {
+	return len(r.oldValues) == 0
+}
+
+// replace copies src to dst doing the replacements and returns how many have been done. Only a window of the stream
+// is held in memory, matches spanning two reads are found nevertheless.
+func (r *streamReplacer) replace(dst io.Writer, src io.Reader) (int, error) {
+	writer := bufio.NewWriter(dst)
+	count := 0
+	buffer := make([]byte, 0, 64*1024)
+	chunk := make([]byte, 32*1024)
+
+	for {
+		readLen, err := src.Read(chunk)
+		buffer = append(buffer, chunk[:readLen]...)
+		eof := err == io.EOF
+		if err != nil && !eof {
+			return count, err
+		}
+
+		// Before EOF, a match starting in the last maxOldLen-1 bytes might continue in the next read
+		scanEnd := len(buffer)
+		if !eof {
+			scanEnd = max(len(buffer)-r.maxOldLen+1, 0)
+		}
+		written := 0
+		for i := 0; i < scanEnd; i++ {
+			for _, candidate := range r.candidates[buffer[i]] {
+				if bytes.HasPrefix(buffer[i:], r.oldValues[candidate]) {
+					if _, err = writer.Write(buffer[written:i]); err != nil {
+						return count, err
+					}
+					if _, err = writer.Write(r.newValues[candidate]); err != nil {
+						return count, err
+					}
+					count++
+					written = i + len(r.oldValues[candidate])
+					i = written - 1
+					break
+				}
+			}
+		}
+		// A match might have reached beyond scanEnd already
+		flushEnd := max(written, scanEnd)
+		if _, err = writer.Write(buffer[written:flushEnd]); err != nil {
+			return count, err
+		}
+		buffer = append(buffer[:0], buffer[flushEnd:]...)
+		if eof {
+			return count, writer.Flush()
+		}
+	}
+}
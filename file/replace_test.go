@@ -0,0 +1,120 @@
+package file_test
+
+import (
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyFileAndReplaceContent(test *testing.T) {
+	testCases := []struct {
+		name         string
+		content      string
+		replacements map[string]string
+		expected     string
+	}{
+		{
+			name:         "no replacements",
+			content:      "name: app",
+			replacements: nil,
+			expected:     "name: app",
+		},
+		{
+			name:         "all occurrences",
+			content:      "app app app",
+			replacements: map[string]string{"app": "svc"},
+			expected:     "svc svc svc",
+		},
+		{
+			name:         "longest key wins",
+			content:      "app_name app",
+			replacements: map[string]string{"app": "svc", "app_name": "my-service"},
+			expected:     "my-service svc",
+		},
+		{
+			name:         "replacements are not chained",
+			content:      "a b",
+			replacements: map[string]string{"a": "b", "b": "c"},
+			expected:     "b c",
+		},
+		{
+			name:         "matches across read boundaries",
+			content:      strings.Repeat("x", 32*1024-3) + "PLACEHOLDER" + strings.Repeat("y", 40*1024),
+			replacements: map[string]string{"PLACEHOLDER": "value"},
+			expected:     strings.Repeat("x", 32*1024-3) + "value" + strings.Repeat("y", 40*1024),
+		},
+		{
+			name:         "binary content is left alone",
+			content:      "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR app",
+			replacements: map[string]string{"app": "svc"},
+			expected:     "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR app",
+		},
+		{
+			name:         "text starting like a bitmap",
+			content:      "BMW_MODEL=__NAME__\n",
+			replacements: map[string]string{"__NAME__": "X5"},
+			expected:     "BMW_MODEL=X5\n",
+		},
+		{
+			name:         "text starting like postscript",
+			content:      "%!PS-Adobe-3.0 __NAME__\n",
+			replacements: map[string]string{"__NAME__": "doc"},
+			expected:     "%!PS-Adobe-3.0 doc\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			dirPath := t.TempDir()
+			srcFilePath := filepath.Join(dirPath, "src")
+			dstFilePath := filepath.Join(dirPath, "dst")
+			file.WriteFile(srcFilePath, testCase.content, true)
+
+			if err := file.CopyFileAndReplaceContent(srcFilePath, dstFilePath, testCase.replacements); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result := file.ReadFile(dstFilePath); result != testCase.expected {
+				t.Errorf("Expected %q but got %q", testCase.expected, result)
+			}
+			dstFileInfo, _ := os.Stat(dstFilePath)
+			if dstFileInfo.Mode().Perm() != 0755 {
+				t.Errorf("Expected the mode to be copied but got %v", dstFileInfo.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestIsBinaryFile(test *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{"empty", "", false},
+		{"text", "hello world\n", false},
+		{"utf-8 text", "grüße 🌍", false},
+		{"nul byte", "hello\x00world", true},
+		{"gzip", "\x1f\x8b\x08\x00\x00\x00\x00\x00", true},
+		{"control bytes", "\x01\x02\x03 some\x04 text\x05", true},
+		{"text starting like a bitmap", "BMW_MODEL=x\n", false},
+		{"terminal colors", "\x1b[31mred\x1b[0m\r\n", false},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "file")
+			file.WriteFile(filePath, testCase.content, false)
+
+			result, err := file.IsBinaryFile(filePath)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != testCase.expected {
+				t.Errorf("Expected %v but got %v", testCase.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+)
+
+// ReplaceRule replaces the matches of a regular expression in a file's content
+type ReplaceRule struct {
+	// Pattern is a regular expression in RE2 syntax, see the regexp package
+	Pattern string
+	// Replacement may refer to capture groups like $1 or ${name}, see regexp.Regexp.Expand - use $$ for a literal $
+	Replacement string
+	// Literal takes Pattern and Replacement as plain strings instead
+	Literal bool
+	// Lines restricts the replacement to the lines matching this regular expression. Matches of Pattern never span
+	// several lines then.
+	Lines string
+	// MaxCount limits the number of replacements per file, 0 means no limit
+	MaxCount int
+}
+
+// ReplaceFileContentWithRules applies the rules one after another to the file's content and rewrites it atomically,
+// keeping its rights. Returns the total number of replacements - the file isn't touched if there were none.
+// Unlike ReplaceFileContentWithRulesFS it doesn't write through the OSFS, which has no atomic replace.
+func ReplaceFileContentWithRules(filePath string, rules ...ReplaceRule) (int, error) {
+	compiledRules, err := compileReplaceRules(rules)
+	if err != nil {
+		return 0, wrapError("replace file content", filePath, err)
+	}
+	fsys, name := osFileFS(filePath)
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return 0, wrapError("replace file content", filePath, err)
+	}
+	newContent, count := compiledRules.apply(content)
+	if count == 0 {
+		return 0, nil
+	}
+	return count, wrapError("replace file content", filePath, writeFileAtomicKeepingMode(filePath, newContent))
+}
+
+// CopyDirAndReplaceContentInFilesWithRules copies a whole directory recursively and applies the rules to all files'
+// contents, binary files are copied as they are
+func CopyDirAndReplaceContentInFilesWithRules(srcDirPath, dstDirPath string, rules ...ReplaceRule) error {
+	return CopyDirWithOptions(srcDirPath, dstDirPath, CopyOptions{ReplaceRules: rules})
+}
+
+// replaceRules is the compiled form of a list of ReplaceRule
+type replaceRules []compiledReplaceRule
+
+type compiledReplaceRule struct {
+	pattern     *regexp.Regexp
+	replacement []byte
+	literal     bool
+	lines       *regexp.Regexp
+	maxCount    int
+}
+
+// compileReplaceRules reports invalid patterns, nil is returned for no rules at all
+func compileReplaceRules(rules []ReplaceRule) (replaceRules, error) {
+	var compiledRules replaceRules
+	for _, rule := range rules {
+		compiledRule := compiledReplaceRule{replacement: []byte(rule.Replacement), literal: rule.Literal,
+			maxCount: rule.MaxCount}
+		pattern := rule.Pattern
+		if rule.Literal {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		var err error
+		if compiledRule.pattern, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid replace pattern %q: %w", rule.Pattern, err)
+		}
+		if rule.Lines != "" {
+			if compiledRule.lines, err = regexp.Compile(rule.Lines); err != nil {
+				return nil, fmt.Errorf("invalid replace lines pattern %q: %w", rule.Lines, err)
+			}
+		}
+		compiledRules = append(compiledRules, compiledRule)
+	}
+	return compiledRules, nil
+}
+
+// apply applies all rules one after another and returns the new content and the number of replacements
+func (r replaceRules) apply(content []byte) ([]byte, int) {
+	total := 0
+	for _, rule := range r {
+		var count int
+		content, count = rule.apply(content)
+		total += count
+	}
+	return content, total
+}
+
+func (r *compiledReplaceRule) apply(content []byte) ([]byte, int) {
+	if r.lines == nil {
+		return r.replace(nil, content, r.maxCount)
+	}
+	var output []byte
+	count := 0
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		lineContent := bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+		remaining := r.maxCount - count
+		if (r.maxCount > 0 && remaining <= 0) || !r.lines.Match(lineContent) {
+			output = append(output, line...)
+			continue
+		}
+		var lineCount int
+		output, lineCount = r.replace(output, lineContent, remaining)
+		output = append(output, line[len(lineContent):]...)
+		count += lineCount
+	}
+	return output, count
+}
+
+// replace appends content with up to maxCount matches (all for 0) replaced to output
+func (r *compiledReplaceRule) replace(output, content []byte, maxCount int) ([]byte, int) {
+	if maxCount <= 0 {
+		maxCount = -1
+	}
+	matches := r.pattern.FindAllSubmatchIndex(content, maxCount)
+	if len(matches) == 0 {
+		return append(output, content...), 0
+	}
+	last := 0
+	for _, match := range matches {
+		output = append(output, content[last:match[0]]...)
+		if r.literal {
+			output = append(output, r.replacement...)
+		} else {
+			output = r.pattern.Expand(output, r.replacement, content, match)
+		}
+		last = match[1]
+	}
+	return append(output, content[last:]...), len(matches)
+}
+
+// replaceContent does the literal replacements and then applies the rules, unless the content is binary
+func replaceContent(ctx context.Context, content []byte, replacer *streamReplacer, rules replaceRules) ([]byte,
+	error) {
+	if isBinaryContent(content[:min(len(content), sniffLen)]) {
+		return content, nil
+	}
+	if !replacer.empty() {
+		var output bytes.Buffer
+		if err := copyContent(ctx, &output, bytes.NewReader(content), replacer); err != nil {
+			return nil, err
+		}
+		content = output.Bytes()
+	}
+	content, _ = rules.apply(content)
+	return content, nil
+}
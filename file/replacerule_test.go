@@ -0,0 +1,159 @@
+package file_test
+
+import (
+	"github.com/investify-tech/go-utils/file"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplaceFileContentWithRules(test *testing.T) {
+	testCases := []struct {
+		name          string
+		content       string
+		rules         []file.ReplaceRule
+		expected      string
+		expectedCount int
+		expectErr     bool
+	}{
+		{
+			name:          "capture groups",
+			content:       "version=1.2.3\nname=app\n",
+			rules:         []file.ReplaceRule{{Pattern: `version=(\d+)\.(\d+)\.\d+`, Replacement: "version=$1.${2}.0"}},
+			expected:      "version=1.2.0\nname=app\n",
+			expectedCount: 1,
+		},
+		{
+			name:          "named groups",
+			content:       "john smith",
+			rules:         []file.ReplaceRule{{Pattern: `(?P<first>\w+) (?P<last>\w+)`, Replacement: "${last}, ${first}"}},
+			expected:      "smith, john",
+			expectedCount: 1,
+		},
+		{
+			name:          "literal",
+			content:       "price: $1.00 (a+b)",
+			rules:         []file.ReplaceRule{{Pattern: "(a+b)", Replacement: "$1", Literal: true}},
+			expected:      "price: $1.00 $1",
+			expectedCount: 1,
+		},
+		{
+			name:          "max count",
+			content:       "a a a a",
+			rules:         []file.ReplaceRule{{Pattern: "a", Replacement: "b", MaxCount: 2}},
+			expected:      "b b a a",
+			expectedCount: 2,
+		},
+		{
+			name:          "matching lines only",
+			content:       "# port=80\nport=80\r\nother=80\nport=80\n",
+			rules:         []file.ReplaceRule{{Pattern: "80", Replacement: "8080", Lines: "^port="}},
+			expected:      "# port=80\nport=8080\r\nother=80\nport=8080\n",
+			expectedCount: 2,
+		},
+		{
+			name:          "matching lines with max count",
+			content:       "x=1\nx=2\nx=3\n",
+			rules:         []file.ReplaceRule{{Pattern: `\d`, Replacement: "0", Lines: "^x", MaxCount: 2}},
+			expected:      "x=0\nx=0\nx=3\n",
+			expectedCount: 2,
+		},
+		{
+			name:    "rules see the previous ones' output",
+			content: "a",
+			rules: []file.ReplaceRule{
+				{Pattern: "a", Replacement: "b"},
+				{Pattern: "b", Replacement: "c"},
+			},
+			expected:      "c",
+			expectedCount: 2,
+		},
+		{
+			name:          "no match",
+			content:       "unchanged",
+			rules:         []file.ReplaceRule{{Pattern: "x", Replacement: "y"}},
+			expected:      "unchanged",
+			expectedCount: 0,
+		},
+		{
+			name:      "invalid pattern",
+			content:   "unchanged",
+			rules:     []file.ReplaceRule{{Pattern: "(", Replacement: "y"}},
+			expected:  "unchanged",
+			expectErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "file.txt")
+			file.WriteFile(filePath, testCase.content, false)
+
+			count, err := file.ReplaceFileContentWithRules(filePath, testCase.rules...)
+			if (err != nil) != testCase.expectErr {
+				t.Fatalf("Expected error: %v, got %v", testCase.expectErr, err)
+			}
+			if count != testCase.expectedCount {
+				t.Errorf("Expected %d replacements, got %d", testCase.expectedCount, count)
+			}
+			if content := file.ReadFile(filePath); content != testCase.expected {
+				t.Errorf("Expected %q, got %q", testCase.expected, content)
+			}
+		})
+	}
+}
+
+func TestCopyDirWithReplaceRules(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	createTree(test, srcDirPath, map[string]string{
+		"go.mod":    "module example.com/old\n\ngo 1.21\n",
+		"image.png": "\x89PNG\r\n\x1a\nold",
+	})
+
+	err := file.CopyDirAndReplaceContentInFilesWithRules(srcDirPath, dstDirPath,
+		file.ReplaceRule{Pattern: `example\.com/(\w+)`, Replacement: "example.org/${1}-new"},
+		file.ReplaceRule{Pattern: `go \d+\.\d+`, Replacement: "go 1.25"})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	if content := file.ReadFile(filepath.Join(dstDirPath, "go.mod")); content != "module example.org/old-new\n\ngo 1.25\n" {
+		test.Errorf("Unexpected content %q", content)
+	}
+	if content := file.ReadFile(filepath.Join(dstDirPath, "image.png")); content != "\x89PNG\r\n\x1a\nold" {
+		test.Errorf("Expected binary file to be copied as it is, got %q", content)
+	}
+}
+
+func TestCopyDirWithReplacementsAndRulesDryRun(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	createTree(test, srcDirPath, map[string]string{"a.txt": "hello old world\n"})
+
+	report, err := file.CopyDirWithReport(srcDirPath, dstDirPath, file.CopyOptions{
+		Replacements: map[string]string{"old": "new"},
+		ReplaceRules: []file.ReplaceRule{{Pattern: `new (\w+)`, Replacement: "$1"}},
+		DryRun:       true,
+	})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(report.Changes) != 2 || !strings.Contains(report.Changes[1].Diff, "+hello world") {
+		test.Errorf("Expected diff of both replacements, got:\n%s", report)
+	}
+}
+
+func TestCopyDirRulesWithTemplate(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	createTree(test, srcDirPath, map[string]string{"a.txt": "a"})
+
+	err := file.CopyDirWithOptions(srcDirPath, filepath.Join(test.TempDir(), "dst"), file.CopyOptions{
+		ReplaceRules: []file.ReplaceRule{{Pattern: "a", Replacement: "b"}},
+		Template:     &file.TemplateOptions{},
+	})
+	if err == nil {
+		test.Errorf("Expected error combining rules and template")
+	}
+}
@@ -0,0 +1,56 @@
+package file
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChangeKind names what happened (or would happen in a dry run) to a path
+type ChangeKind string
+
+const (
+	ChangeCreate    ChangeKind = "create"
+	ChangeOverwrite ChangeKind = "overwrite"
+	ChangeRename    ChangeKind = "rename"
+	ChangeDelete    ChangeKind = "delete"
+)
+
+// Change is a single entry of a Report
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// Path is the path which is created, overwritten, renamed to or deleted
+	Path string `json:"path"`
+	// Source is the path copied from or renamed from
+	Source string `json:"source,omitempty"`
+	IsDir  bool   `json:"isDir,omitempty"`
+	// Diff shows the content changes done by replacements or templates in unified diff format - dry runs only
+	Diff string `json:"diff,omitempty"`
+}
+
+// Report lists the changes of a file operation in the order they are done. In a dry run, it's the plan of what would
+// be done. It can be printed as it is or marshalled to JSON.
+type Report struct {
+	DryRun  bool     `json:"dryRun"`
+	Changes []Change `json:"changes"`
+}
+
+func (r *Report) add(change Change) {
+	r.Changes = append(r.Changes, change)
+}
+
+// String lists the changes one per line, followed by their diffs if there are any
+func (r *Report) String() string {
+	var builder strings.Builder
+	for _, change := range r.Changes {
+		target := change.Path
+		if change.IsDir {
+			target += "/"
+		}
+		if change.Kind == ChangeRename {
+			target = change.Source + " -> " + target
+		}
+		fmt.Fprintf(&builder, "%-9s %s\n", change.Kind, target)
+		builder.WriteString(change.Diff)
+	}
+	return builder.String()
+}
@@ -0,0 +1,124 @@
+package file_test
+
+import (
+	"encoding/json"
+	"github.com/investify-tech/go-utils/file"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCopyDirWithReportDryRun(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	createTree(test, srcDirPath, map[string]string{"conf/app.yaml": "name: APP\nport: 8080\n", "README.md": "APP"})
+	createTree(test, dstDirPath, map[string]string{"README.md": "old"})
+
+	options := file.CopyOptions{Replacements: map[string]string{"APP": "billing"}, DryRun: true}
+	report, err := file.CopyDirWithReport(srcDirPath, dstDirPath, options)
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []file.Change{
+		{Kind: file.ChangeOverwrite, Path: filepath.Join(dstDirPath, "README.md"), Source: filepath.Join(srcDirPath, "README.md")},
+		{Kind: file.ChangeCreate, Path: filepath.Join(dstDirPath, "conf"), Source: filepath.Join(srcDirPath, "conf"), IsDir: true},
+		{Kind: file.ChangeCreate, Path: filepath.Join(dstDirPath, "conf", "app.yaml"), Source: filepath.Join(srcDirPath, "conf", "app.yaml")},
+	}
+	if len(report.Changes) != len(expected) {
+		test.Fatalf("Expected %d changes but got %v", len(expected), report.Changes)
+	}
+	for i, change := range report.Changes {
+		change.Diff = ""
+		if !reflect.DeepEqual(change, expected[i]) {
+			test.Errorf("Expected change %v but got %v", expected[i], change)
+		}
+	}
+	if !strings.Contains(report.Changes[2].Diff, "-name: APP\n+name: billing\n port: 8080\n") {
+		test.Errorf("Expected a diff of the replacement but got %q", report.Changes[2].Diff)
+	}
+	if file.ReadFile(filepath.Join(dstDirPath, "README.md")) != "old" || file.Exists(filepath.Join(dstDirPath, "conf")) {
+		test.Errorf("Expected the dry run not to touch the destination")
+	}
+}
+
+func TestCopyDirWithReport(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	createTree(test, srcDirPath, map[string]string{"a/b.txt": "b"})
+
+	report, err := file.CopyDirWithReport(srcDirPath, dstDirPath, file.CopyOptions{})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "create    " + dstDirPath + "/\n" +
+		"create    " + filepath.Join(dstDirPath, "a") + "/\n" +
+		"create    " + filepath.Join(dstDirPath, "a", "b.txt") + "\n"
+	if report.String() != expected {
+		test.Errorf("Expected %q but got %q", expected, report.String())
+	}
+	if file.ReadFile(filepath.Join(dstDirPath, "a", "b.txt")) != "b" {
+		test.Errorf("Expected the file to be copied")
+	}
+	if _, err = json.Marshal(report); err != nil {
+		test.Errorf("Expected the report to be marshallable but got %v", err)
+	}
+}
+
+func TestRenameFilesInDirWithReport(test *testing.T) {
+	testCases := []struct {
+		name   string
+		dryRun bool
+	}{
+		{name: "dry run", dryRun: true},
+		{name: "real run", dryRun: false},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			dirPath := t.TempDir()
+			createTree(t, dirPath, map[string]string{"app/app.go": "package app", "app/util.go": "package app"})
+
+			options := file.RenameOptions{Replacements: map[string]string{"app": "billing"}, DryRun: testCase.dryRun}
+			report, err := file.RenameFilesInDirWithReport(dirPath, options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expected := "rename    " + filepath.Join(dirPath, "app", "app.go") + " -> " + filepath.Join(dirPath, "app", "billing.go") + "\n" +
+				"rename    " + filepath.Join(dirPath, "app") + " -> " + filepath.Join(dirPath, "billing") + "/\n"
+			if report.String() != expected {
+				t.Errorf("Expected %q but got %q", expected, report.String())
+			}
+			expectedTree := []string{"billing", "billing/billing.go", "billing/util.go"}
+			if testCase.dryRun {
+				expectedTree = []string{"app", "app/app.go", "app/util.go"}
+			}
+			if result := listTree(t, dirPath); !reflect.DeepEqual(result, expectedTree) {
+				t.Errorf("Expected %v but got %v", expectedTree, result)
+			}
+		})
+	}
+}
+
+func TestCopyDirAndRenameFilesWithReportDryRun(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	createTree(test, srcDirPath, map[string]string{"app.go": "package app"})
+
+	options := file.CopyOptions{DryRun: true}
+	report, err := file.CopyDirAndRenameFilesWithReport(srcDirPath, dstDirPath, map[string]string{"app": "svc"}, options)
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	lastChange := report.Changes[len(report.Changes)-1]
+	if lastChange.Kind != file.ChangeRename || lastChange.Path != filepath.Join(dstDirPath, "svc.go") {
+		test.Errorf("Expected the planned copy to be renamed but got %v", report.Changes)
+	}
+	if file.Exists(dstDirPath) {
+		test.Errorf("Expected the dry run not to create the destination")
+	}
+}
@@ -0,0 +1,167 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// SyncCompare tells SyncDir how to find out if a destination file is up-to-date
+type SyncCompare int
+
+const (
+	// SyncBySizeAndTime treats files with equal size and modification time (in seconds) as equal, like rsync does
+	SyncBySizeAndTime SyncCompare = iota
+	// SyncByContent compares the contents of files with equal size
+	SyncByContent
+)
+
+// SyncOptions configures SyncDir
+type SyncOptions struct {
+	// FilterOptions select the entries to sync, in both source and destination. Excluded destination entries are
+	// never deleted.
+	FilterOptions
+	Compare SyncCompare
+	// Delete removes destination entries which don't exist in the source
+	Delete bool
+	// DryRun doesn't touch the destination but only plans what would be done
+	DryRun bool
+	// Concurrency is the number of files copied in parallel, see CopyOptions
+	Concurrency int
+}
+
+// SyncDir mirrors the source dir into the destination dir: only new and changed files are copied, links are
+// recreated as links and modification times are preserved so that the next sync can skip unchanged files. Destination
+// entries of another type than their source (e.g. a dir where the source has a file) are replaced. The report lists
+// the deletions first, followed by the copies.
+func SyncDir(srcDirPath, dstDirPath string, options SyncOptions) (*Report, error) {
+	return SyncDirContext(context.Background(), srcDirPath, dstDirPath, options)
+}
+
+// SyncDirContext is SyncDir stopping as soon as possible when the context is done
+func SyncDirContext(ctx context.Context, srcDirPath, dstDirPath string, options SyncOptions) (*Report, error) {
+	// A missing or mistyped source would make every destination entry look extraneous
+	srcDirInfo, err := os.Stat(srcDirPath)
+	if err != nil {
+		return &Report{DryRun: options.DryRun}, wrapError("sync", srcDirPath, err)
+	}
+	if !srcDirInfo.IsDir() {
+		return &Report{DryRun: options.DryRun}, wrapError("sync", srcDirPath, errors.New("not a dir"))
+	}
+
+	report, err := deleteOutOfSync(srcDirPath, dstDirPath, options)
+	if err != nil {
+		return report, err
+	}
+
+	copyOptions := CopyOptions{
+		FilterOptions: options.FilterOptions,
+		Symlinks:      SymlinksPreserve,
+		SpecialFiles:  SpecialFilesSkip,
+		PreserveTimes: true,
+		DryRun:        options.DryRun,
+		Concurrency:   options.Concurrency,
+	}
+	unchanged := func(srcPath, dstPath string, srcInfo os.FileInfo) bool {
+		return syncedAlready(srcPath, dstPath, srcInfo, options.Compare)
+	}
+	copyReport, err := copyDir(ctx, OSFS(srcDirPath), ".", OSFS(dstDirPath), ".", copyOptions, unchanged)
+	report.Changes = append(report.Changes, copyReport.Changes...)
+	return report, err
+}
+
+// deleteOutOfSync deletes destination entries missing in the source (if configured) or having another type
+func deleteOutOfSync(srcDirPath, dstDirPath string, options SyncOptions) (*Report, error) {
+	report := &Report{DryRun: options.DryRun}
+	filter, err := NewFilter(options.FilterOptions)
+	if err != nil {
+		return report, wrapError("sync", srcDirPath, err)
+	}
+	if _, err = os.Lstat(dstDirPath); errors.Is(err, fs.ErrNotExist) {
+		return report, nil
+	}
+
+	err = walkFiltered(dstDirPath, filter, func(entryPath, relPath string, dirEntry fs.DirEntry) error {
+		srcInfo, err := os.Lstat(filepath.Join(srcDirPath, filepath.FromSlash(relPath)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		missing := err != nil
+		if !missing && srcInfo.Mode().Type() == dirEntry.Type() {
+			return nil
+		}
+		if missing && !options.Delete {
+			return nil
+		}
+		if !options.DryRun {
+			if err = os.RemoveAll(entryPath); err != nil {
+				return err
+			}
+		}
+		report.add(Change{Kind: ChangeDelete, Path: entryPath, IsDir: dirEntry.IsDir()})
+		if dirEntry.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return report, wrapError("sync", dstDirPath, err)
+}
+
+// syncedAlready tells if the destination is up-to-date with the source
+func syncedAlready(srcPath, dstPath string, srcInfo os.FileInfo, compare SyncCompare) bool {
+	dstInfo, err := os.Lstat(dstPath)
+	if err != nil || dstInfo.Mode() != srcInfo.Mode() {
+		return false
+	}
+	if srcInfo.Mode()&os.ModeSymlink != 0 {
+		srcTarget, srcErr := os.Readlink(srcPath)
+		dstTarget, dstErr := os.Readlink(dstPath)
+		return srcErr == nil && dstErr == nil && srcTarget == dstTarget
+	}
+	if dstInfo.Size() != srcInfo.Size() {
+		return false
+	}
+	if compare == SyncByContent {
+		equal, err := sameContent(srcPath, dstPath)
+		return err == nil && equal
+	}
+	return dstInfo.ModTime().Unix() == srcInfo.ModTime().Unix()
+}
+
+// sameContent compares two files chunk by chunk, stopping at the first difference
+func sameContent(filePath1, filePath2 string) (bool, error) {
+	fileRef1, err := os.Open(filePath1)
+	if err != nil {
+		return false, err
+	}
+	defer fileRef1.Close()
+	fileRef2, err := os.Open(filePath2)
+	if err != nil {
+		return false, err
+	}
+	defer fileRef2.Close()
+
+	reader1, reader2 := bufio.NewReader(fileRef1), bufio.NewReader(fileRef2)
+	chunk1, chunk2 := make([]byte, 32*1024), make([]byte, 32*1024)
+	for {
+		readLen1, err1 := io.ReadFull(reader1, chunk1)
+		readLen2, err2 := io.ReadFull(reader2, chunk2)
+		if !bytes.Equal(chunk1[:readLen1], chunk2[:readLen2]) {
+			return false, nil
+		}
+		if err1 == io.EOF || err1 == io.ErrUnexpectedEOF {
+			return err2 == io.EOF || err2 == io.ErrUnexpectedEOF, nil
+		}
+		if err1 != nil {
+			return false, err1
+		}
+		if err2 != nil {
+			return false, err2
+		}
+	}
+}
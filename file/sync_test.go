@@ -0,0 +1,155 @@
+package file_test
+
+import (
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSyncDir(test *testing.T) {
+	testCases := []struct {
+		name          string
+		options       file.SyncOptions
+		expectedTree  []string
+		expectedKinds []file.ChangeKind
+	}{
+		{
+			name:          "without delete",
+			options:       file.SyncOptions{},
+			expectedTree:  []string{"changed.txt", "extra.txt", "kept.txt", "new.txt", "typed", "typed/file.txt"},
+			expectedKinds: []file.ChangeKind{file.ChangeDelete, file.ChangeOverwrite, file.ChangeCreate, file.ChangeCreate, file.ChangeCreate},
+		},
+		{
+			name:          "with delete",
+			options:       file.SyncOptions{Delete: true},
+			expectedTree:  []string{"changed.txt", "kept.txt", "new.txt", "typed", "typed/file.txt"},
+			expectedKinds: []file.ChangeKind{file.ChangeDelete, file.ChangeDelete, file.ChangeOverwrite, file.ChangeCreate, file.ChangeCreate, file.ChangeCreate},
+		},
+		{
+			name:          "by content",
+			options:       file.SyncOptions{Compare: file.SyncByContent},
+			expectedTree:  []string{"changed.txt", "extra.txt", "kept.txt", "new.txt", "typed", "typed/file.txt"},
+			expectedKinds: []file.ChangeKind{file.ChangeDelete, file.ChangeOverwrite, file.ChangeCreate, file.ChangeCreate, file.ChangeCreate},
+		},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			srcDirPath := filepath.Join(t.TempDir(), "src")
+			dstDirPath := filepath.Join(t.TempDir(), "dst")
+			createTree(t, srcDirPath, map[string]string{
+				"kept.txt": "same", "changed.txt": "new", "new.txt": "new", "typed/file.txt": "file",
+			})
+			createTree(t, dstDirPath, map[string]string{
+				"kept.txt": "same", "changed.txt": "old", "extra.txt": "extra", "typed": "was a file",
+			})
+			modTime := time.Now().Add(-time.Hour)
+			for _, filePath := range []string{filepath.Join(srcDirPath, "kept.txt"), filepath.Join(dstDirPath, "kept.txt")} {
+				if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// same size as the source, but older
+			if err := os.Chtimes(filepath.Join(dstDirPath, "changed.txt"), modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := file.SyncDir(srcDirPath, dstDirPath, testCase.options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if tree := listTree(t, dstDirPath); !reflect.DeepEqual(tree, testCase.expectedTree) {
+				t.Errorf("Expected tree %v, got %v", testCase.expectedTree, tree)
+			}
+			var kinds []file.ChangeKind
+			for _, change := range report.Changes {
+				kinds = append(kinds, change.Kind)
+			}
+			if !reflect.DeepEqual(kinds, testCase.expectedKinds) {
+				t.Errorf("Expected changes %v, got:\n%s", testCase.expectedKinds, report)
+			}
+			if content := file.ReadFile(filepath.Join(dstDirPath, "changed.txt")); content != "new" {
+				t.Errorf("Expected changed.txt to be synced, got %q", content)
+			}
+		})
+	}
+}
+
+func TestSyncDirTwiceIsNoop(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	createTree(test, srcDirPath, map[string]string{"a.txt": "a", "sub/b.txt": "b"})
+	if err := os.Symlink("a.txt", filepath.Join(srcDirPath, "link.txt")); err != nil {
+		test.Skipf("Symlinks not supported: %v", err)
+	}
+
+	if _, err := file.SyncDir(srcDirPath, dstDirPath, file.SyncOptions{Delete: true}); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	report, err := file.SyncDir(srcDirPath, dstDirPath, file.SyncOptions{Delete: true})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	for _, change := range report.Changes {
+		if !change.IsDir {
+			test.Errorf("Expected no file changes on second sync, got:\n%s", report)
+			break
+		}
+	}
+}
+
+func TestSyncDirDryRun(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	createTree(test, srcDirPath, map[string]string{"new.txt": "new"})
+	createTree(test, dstDirPath, map[string]string{"extra.txt": "extra"})
+
+	report, err := file.SyncDir(srcDirPath, dstDirPath, file.SyncOptions{Delete: true, DryRun: true})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	if tree := listTree(test, dstDirPath); !reflect.DeepEqual(tree, []string{"extra.txt"}) {
+		test.Errorf("Expected destination to be untouched, got %v", tree)
+	}
+	if len(report.Changes) != 2 || report.Changes[0].Kind != file.ChangeDelete {
+		test.Errorf("Expected a delete and a create, got:\n%s", report)
+	}
+}
+
+func TestSyncDirInvalidSource(test *testing.T) {
+	srcFilePath := filepath.Join(test.TempDir(), "file.txt")
+	file.WriteFile(srcFilePath, "content", false)
+
+	testCases := []struct {
+		name       string
+		srcDirPath string
+	}{
+		{name: "missing", srcDirPath: filepath.Join(test.TempDir(), "missing")},
+		{name: "file", srcDirPath: srcFilePath},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			dstDirPath := t.TempDir()
+			createTree(t, dstDirPath, map[string]string{"important.txt": "keep", "sub/file.txt": "keep"})
+
+			report, err := file.SyncDir(testCase.srcDirPath, dstDirPath, file.SyncOptions{Delete: true})
+
+			if err == nil {
+				t.Errorf("Expected an error for an invalid source")
+			}
+			if len(report.Changes) != 0 {
+				t.Errorf("Expected no changes but got %v", report.Changes)
+			}
+			expected := []string{"important.txt", "sub", "sub/file.txt"}
+			if result := listTree(t, dstDirPath); !reflect.DeepEqual(result, expected) {
+				t.Errorf("Expected %v to be kept but got %v", expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package file
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// TempOptions configures the temp file and dir helpers
+type TempOptions struct {
+	// Dir is the dir to create the temp file or dir in, empty means os.TempDir()
+	Dir string
+	// Pattern is the name, the last "*" is replaced by a random string (appended if there is none) - see os.MkdirTemp
+	Pattern string
+	// KeepOnFailure keeps the temp file or dir of a failed test for debugging and logs its path. Only used by the
+	// TestingTB variants.
+	KeepOnFailure bool
+}
+
+// TempDir creates a new temp dir and returns its path together with a function deleting it again. Defer the cleanup
+// right away, so that the dir is deleted on panics, too. The cleanup also deletes read-only content.
+func TempDir(options TempOptions) (string, func() error, error) {
+	dirPath, err := os.MkdirTemp(options.Dir, options.Pattern)
+	if err != nil {
+		return "", nil, wrapError("create temp dir", options.Dir, err)
+	}
+	cleanup := func() error {
+		return wrapError("remove temp dir", dirPath, removeAllWritable(dirPath))
+	}
+	return dirPath, cleanup, nil
+}
+
+// TempFile creates a new temp file opened for reading and writing and returns it together with a function closing and
+// deleting it again. Defer the cleanup right away, so that the file is deleted on panics, too.
+func TempFile(options TempOptions) (*os.File, func() error, error) {
+	fileRef, err := os.CreateTemp(options.Dir, options.Pattern)
+	if err != nil {
+		return nil, nil, wrapError("create temp file", options.Dir, err)
+	}
+	cleanup := func() error {
+		// The file may have been closed by the caller already
+		_ = fileRef.Close()
+		err := os.Remove(fileRef.Name())
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return wrapError("remove temp file", fileRef.Name(), err)
+	}
+	return fileRef, cleanup, nil
+}
+
+// TestingTB is the part of testing.TB needed by TestTempDir and TestTempFile, which testing.TB satisfies. It keeps the
+// testing package out of binaries using this package.
+type TestingTB interface {
+	Helper()
+	Cleanup(cleanup func())
+	Failed() bool
+	Logf(format string, args ...any)
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+}
+
+// TestTempDir is TempDir for tests: the dir is deleted by tb.Cleanup when the test ends (unless KeepOnFailure is set
+// and the test failed), errors fail the test. Unlike tb.TempDir, the dir can be placed anywhere and kept for debugging.
+func TestTempDir(tb TestingTB, options TempOptions) string {
+	tb.Helper()
+	dirPath, cleanup, err := TempDir(options)
+	if err != nil {
+		tb.Fatalf("Creating temp dir failed: %v", err)
+	}
+	registerTempCleanup(tb, dirPath, options, cleanup)
+	return dirPath
+}
+
+// TestTempFile is TempFile for tests: the file is closed and deleted by tb.Cleanup when the test ends (unless
+// KeepOnFailure is set and the test failed), errors fail the test
+func TestTempFile(tb TestingTB, options TempOptions) *os.File {
+	tb.Helper()
+	fileRef, cleanup, err := TempFile(options)
+	if err != nil {
+		tb.Fatalf("Creating temp file failed: %v", err)
+	}
+	registerTempCleanup(tb, fileRef.Name(), options, cleanup)
+	return fileRef
+}
+
+func registerTempCleanup(tb TestingTB, tempPath string, options TempOptions, cleanup func() error) {
+	tb.Cleanup(func() {
+		if options.KeepOnFailure && tb.Failed() {
+			tb.Logf("Keeping %s of failed test", tempPath)
+			return
+		}
+		if err := cleanup(); err != nil {
+			tb.Errorf("Cleaning up failed: %v", err)
+		}
+	})
+}
+
+// removeAllWritable is os.RemoveAll, which makes read-only dirs writable first if needed
+func removeAllWritable(dirPath string) error {
+	if err := os.RemoveAll(dirPath); err == nil {
+		return nil
+	}
+	_ = filepath.WalkDir(dirPath, func(entryPath string, dirEntry fs.DirEntry, err error) error {
+		if err == nil && dirEntry.IsDir() {
+			_ = os.Chmod(entryPath, 0700)
+		}
+		return nil
+	})
+	return os.RemoveAll(dirPath)
+}
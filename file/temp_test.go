@@ -0,0 +1,109 @@
+package file_test
+
+import (
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTempDir(test *testing.T) {
+	parentDirPath := test.TempDir()
+
+	dirPath, cleanup, err := file.TempDir(file.TempOptions{Dir: parentDirPath, Pattern: "scratch-*"})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	if filepath.Dir(dirPath) != parentDirPath || !strings.HasPrefix(filepath.Base(dirPath), "scratch-") {
+		test.Errorf("Unexpected temp dir %s", dirPath)
+	}
+	createTree(test, dirPath, map[string]string{"sub/file.txt": "content"})
+	if err = os.Chmod(filepath.Join(dirPath, "sub"), 0500); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = cleanup(); err != nil {
+		test.Fatalf("Unexpected error cleaning up: %v", err)
+	}
+	if file.Exists(dirPath) {
+		test.Errorf("Expected the temp dir to be deleted, read-only content included")
+	}
+}
+
+func TestTempFile(test *testing.T) {
+	fileRef, cleanup, err := file.TempFile(file.TempOptions{Dir: test.TempDir(), Pattern: "*.json"})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(fileRef.Name(), ".json") {
+		test.Errorf("Unexpected temp file %s", fileRef.Name())
+	}
+	if _, err = fileRef.WriteString("{}"); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = cleanup(); err != nil {
+		test.Fatalf("Unexpected error cleaning up: %v", err)
+	}
+	if file.Exists(fileRef.Name()) {
+		test.Errorf("Expected the temp file to be deleted")
+	}
+	if err = cleanup(); err != nil {
+		test.Errorf("Expected a second cleanup to be fine, got %v", err)
+	}
+}
+
+// testing.TB has to satisfy the interface of the helpers
+var _ file.TestingTB = testing.TB(nil)
+
+// fakeTB records cleanups instead of running them at the end of the test, and can pretend to have failed
+type fakeTB struct {
+	testing.TB
+	failed   bool
+	cleanups []func()
+	logs     []string
+}
+
+func (tb *fakeTB) Helper()                      {}
+func (tb *fakeTB) Failed() bool                 { return tb.failed }
+func (tb *fakeTB) Cleanup(cleanup func())       { tb.cleanups = append(tb.cleanups, cleanup) }
+func (tb *fakeTB) Logf(format string, _ ...any) { tb.logs = append(tb.logs, format) }
+
+func (tb *fakeTB) runCleanups() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+}
+
+func TestTestTempDirAndFile(test *testing.T) {
+	testCases := []struct {
+		name          string
+		failed        bool
+		keepOnFailure bool
+		expectKept    bool
+	}{
+		{name: "passed", failed: false, keepOnFailure: true, expectKept: false},
+		{name: "failed", failed: true, keepOnFailure: false, expectKept: false},
+		{name: "failed and kept", failed: true, keepOnFailure: true, expectKept: true},
+	}
+
+	for _, testCase := range testCases {
+		test.Run(testCase.name, func(t *testing.T) {
+			tb := &fakeTB{TB: t, failed: testCase.failed}
+			options := file.TempOptions{Dir: t.TempDir(), KeepOnFailure: testCase.keepOnFailure}
+
+			dirPath := file.TestTempDir(tb, options)
+			fileRef := file.TestTempFile(tb, options)
+			tb.runCleanups()
+
+			if file.Exists(dirPath) != testCase.expectKept || file.Exists(fileRef.Name()) != testCase.expectKept {
+				t.Errorf("Expected kept: %v, got dir %v, file %v", testCase.expectKept, file.Exists(dirPath),
+					file.Exists(fileRef.Name()))
+			}
+			if (len(tb.logs) > 0) != testCase.expectKept {
+				t.Errorf("Expected the kept paths to be logged, got %v", tb.logs)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package file
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"text/template"
+)
+
+// NoTemplateMarker is the default opt-out marker of TemplateOptions
+const NoTemplateMarker = "go-utils:no-template"
+
+// TemplateOptions configures the rendering of copied files through text/template. Binary files and files containing
+// the opt-out marker within their first 512 bytes are copied as they are. Missing map keys are reported as errors
+// instead of being rendered as "<no value>".
+type TemplateOptions struct {
+	// Data is passed to every template execution
+	Data any
+	// Funcs are made available in addition to the builtin template functions
+	Funcs template.FuncMap
+	// RenderNames renders the names of files and dirs, too - an entry whose name renders to "" is skipped
+	RenderNames bool
+	// OptOutMarker overrides NoTemplateMarker
+	OptOutMarker string
+}
+
+// TemplateError points to the file (relative to the copied dir) and, if known, the line a template failed in
+type TemplateError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+// Error returns the text/template error which already names file and line, as templates are named after the path
+func (e *TemplateError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// templateLineRegex extracts the line from text/template errors like "template: name:12:5: executing ..."
+var templateLineRegex = regexp.MustCompile(`^template: .*?:(\d+):`)
+
+func newTemplateError(relPath string, err error) error {
+	templateError := &TemplateError{Path: relPath, Err: err}
+	if match := templateLineRegex.FindStringSubmatch(err.Error()); match != nil {
+		templateError.Line, _ = strconv.Atoi(match[1])
+	}
+	return templateError
+}
+
+// renderTemplate parses and executes a single template named after the given path
+func (o *TemplateOptions) renderTemplate(relPath string, text string) ([]byte, error) {
+	parsedTemplate, err := template.New(relPath).Funcs(o.Funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, newTemplateError(relPath, err)
+	}
+	var output bytes.Buffer
+	if err = parsedTemplate.Execute(&output, o.Data); err != nil {
+		return nil, newTemplateError(relPath, err)
+	}
+	return output.Bytes(), nil
+}
+
+// renderName renders a file or dir name if configured, otherwise the name is returned unchanged
+func (o *TemplateOptions) renderName(relPath string, name string) (string, error) {
+	if o == nil || !o.RenderNames {
+		return name, nil
+	}
+	renderedName, err := o.renderTemplate(relPath, name)
+	return string(renderedName), err
+}
+
+// renderContent renders a file's content unless it's binary or contains the opt-out marker
+func (o *TemplateOptions) renderContent(relPath string, content []byte) ([]byte, error) {
+	optOutMarker := o.OptOutMarker
+	if optOutMarker == "" {
+		optOutMarker = NoTemplateMarker
+	}
+	sample := content[:min(len(content), sniffLen)]
+	if isBinaryContent(sample) || bytes.Contains(sample, []byte(optOutMarker)) {
+		return content, nil
+	}
+	return o.renderTemplate(relPath, string(content))
+}
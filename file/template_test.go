@@ -0,0 +1,69 @@
+package file_test
+
+import (
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestCopyDirWithOptionsTemplate(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	dstDirPath := filepath.Join(test.TempDir(), "dst")
+	createTree(test, srcDirPath, map[string]string{
+		"{{.Name}}/main.go":               "package {{.Name}} // {{upper .Name}}",
+		"{{.Name}}/raw.tmpl":              "// go-utils:no-template\n{{.Kept}}",
+		"{{if .Docker}}Dockerfile{{end}}": "FROM scratch",
+		"logo.png":                        "\x89PNG\r\n\x1a\n\x00\x00{{.Name}}",
+	})
+	options := file.CopyOptions{Template: &file.TemplateOptions{
+		Data:        map[string]any{"Name": "billing", "Docker": false},
+		Funcs:       template.FuncMap{"upper": strings.ToUpper},
+		RenderNames: true,
+	}}
+
+	if err := file.CopyDirWithOptions(srcDirPath, dstDirPath, options); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedTree := []string{"billing", "billing/main.go", "billing/raw.tmpl", "logo.png"}
+	if result := listTree(test, dstDirPath); !reflect.DeepEqual(result, expectedTree) {
+		test.Errorf("Expected %v but got %v", expectedTree, result)
+	}
+	expectedContents := map[string]string{
+		"billing/main.go":  "package billing // BILLING",
+		"billing/raw.tmpl": "// go-utils:no-template\n{{.Kept}}",
+		"logo.png":         "\x89PNG\r\n\x1a\n\x00\x00{{.Name}}",
+	}
+	for relPath, expected := range expectedContents {
+		if result := file.ReadFile(filepath.Join(dstDirPath, relPath)); result != expected {
+			test.Errorf("Expected %s to contain %q but got %q", relPath, expected, result)
+		}
+	}
+}
+
+func TestCopyDirWithOptionsTemplateError(test *testing.T) {
+	srcDirPath := filepath.Join(test.TempDir(), "src")
+	createTree(test, srcDirPath, map[string]string{"conf/app.yaml": "name: app\nport: {{.Port}}\n"})
+	options := file.CopyOptions{Template: &file.TemplateOptions{Data: map[string]any{}}}
+
+	err := file.CopyDirWithOptions(srcDirPath, filepath.Join(test.TempDir(), "dst"), options)
+
+	var templateError *file.TemplateError
+	if !errors.As(err, &templateError) {
+		test.Fatalf("Expected a TemplateError but got %v", err)
+	}
+	if templateError.Path != "conf/app.yaml" || templateError.Line != 2 {
+		test.Errorf("Expected the error to point to conf/app.yaml:2 but got %s:%d", templateError.Path, templateError.Line)
+	}
+}
+
+func TestCopyDirWithOptionsTemplateAndReplacements(test *testing.T) {
+	options := file.CopyOptions{Replacements: map[string]string{"a": "b"}, Template: &file.TemplateOptions{}}
+	if err := file.CopyDirWithOptions(test.TempDir(), test.TempDir(), options); err == nil {
+		test.Errorf("Expected an error when combining templates and replacements")
+	}
+}
@@ -0,0 +1,181 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// ErrTransactionDone is reported when using a Transaction after it was committed or rolled back
+var ErrTransactionDone = errors.New("transaction already committed or rolled back")
+
+// Transaction records the state of files before they are changed, so that a multi-step operation can be rolled back
+// as a whole. Files are tracked on their first change through the transaction's methods (or explicitly by Track):
+// existing files are saved to a temp dir, new files are removed again on rollback. Dirs aren't tracked, a dir created
+// for a new file stays. A Transaction is safe for concurrent use.
+type Transaction struct {
+	mutex   sync.Mutex
+	tempDir string
+	files   []*trackedFile
+	tracked map[string]*trackedFile
+	done    bool
+}
+
+// trackedFile is the state of a file before the transaction changed it
+type trackedFile struct {
+	path string
+	// savedPath holds a copy of the original file, it's empty if the file didn't exist
+	savedPath string
+}
+
+// NewTransaction starts a transaction, which has to be ended by Commit or Rollback to remove its temp dir
+func NewTransaction() (*Transaction, error) {
+	tempDir, err := os.MkdirTemp("", "go-utils-transaction-*")
+	if err != nil {
+		return nil, wrapError("start transaction", os.TempDir(), err)
+	}
+	return &Transaction{tempDir: tempDir, tracked: make(map[string]*trackedFile)}, nil
+}
+
+// WithTransaction runs fn within a new transaction, which is committed if fn succeeds and rolled back if it returns an
+// error or panics. A failing rollback is reported together with fn's error.
+func WithTransaction(fn func(tx *Transaction) error) (err error) {
+	tx, err := NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			_ = tx.Rollback()
+			panic(recovered)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// Track records the current state of files which are about to be changed outside of the transaction's methods.
+// Tracking a file again keeps its first recorded state.
+func (t *Transaction) Track(filePaths ...string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, filePath := range filePaths {
+		if err := t.track(filePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFile is WriteFileE within the transaction
+func (t *Transaction) WriteFile(dstFilePath string, fileContent string, executable bool) error {
+	if err := t.Track(dstFilePath); err != nil {
+		return err
+	}
+	return WriteFileE(dstFilePath, fileContent, executable)
+}
+
+// ReplaceFileContent is ReplaceFileContentE within the transaction
+func (t *Transaction) ReplaceFileContent(filePath string, value string, replacement string) error {
+	if err := t.Track(filePath); err != nil {
+		return err
+	}
+	return ReplaceFileContentE(filePath, value, replacement)
+}
+
+// CopyFile is CopyFile within the transaction
+func (t *Transaction) CopyFile(srcPath, dstPath string) error {
+	if err := t.Track(dstPath); err != nil {
+		return err
+	}
+	return wrapError("copy file", srcPath, CopyFile(srcPath, dstPath))
+}
+
+// RemoveFile removes a file within the transaction, a missing file is no error
+func (t *Transaction) RemoveFile(filePath string) error {
+	if err := t.Track(filePath); err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return wrapError("remove file", filePath, err)
+	}
+	return nil
+}
+
+// Commit keeps all changes and discards the recorded states
+func (t *Transaction) Commit() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.done {
+		return ErrTransactionDone
+	}
+	t.done = true
+	return wrapError("commit transaction", t.tempDir, removeAllWritable(t.tempDir))
+}
+
+// Rollback restores all tracked files in reverse order of their tracking: changed files get their original content,
+// mode and modification time back, created files are removed. It tries all files, reporting all failures at once.
+func (t *Transaction) Rollback() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.done {
+		return ErrTransactionDone
+	}
+	t.done = true
+
+	var errs []error
+	for i := len(t.files) - 1; i >= 0; i-- {
+		trackedFile := t.files[i]
+		var err error
+		if trackedFile.savedPath != "" {
+			err = copyFileAtomic(trackedFile.savedPath, trackedFile.path)
+		} else if err = os.Remove(trackedFile.path); os.IsNotExist(err) {
+			err = nil
+		}
+		errs = append(errs, wrapError("roll back", trackedFile.path, err))
+	}
+	if err := errors.Join(errs...); err != nil {
+		// The saved originals are kept for manual recovery
+		return fmt.Errorf("%w (originals kept in %s)", err, t.tempDir)
+	}
+	return wrapError("roll back transaction", t.tempDir, removeAllWritable(t.tempDir))
+}
+
+func (t *Transaction) track(filePath string) error {
+	if t.done {
+		return ErrTransactionDone
+	}
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return wrapError("track file", filePath, err)
+	}
+	if _, tracked := t.tracked[absFilePath]; tracked {
+		return nil
+	}
+
+	trackedFile := &trackedFile{path: absFilePath}
+	fileInfo, err := os.Lstat(absFilePath)
+	if err == nil {
+		if !fileInfo.Mode().IsRegular() {
+			return wrapError("track file", filePath, errors.New("not a regular file"))
+		}
+		trackedFile.savedPath = filepath.Join(t.tempDir, strconv.Itoa(len(t.files)))
+		if err = copyFileAtomic(absFilePath, trackedFile.savedPath); err != nil {
+			return wrapError("track file", filePath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return wrapError("track file", filePath, err)
+	}
+	t.files = append(t.files, trackedFile)
+	t.tracked[absFilePath] = trackedFile
+	return nil
+}
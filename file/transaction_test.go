@@ -0,0 +1,127 @@
+package file_test
+
+import (
+	"errors"
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithTransactionRollback(test *testing.T) {
+	dirPath := test.TempDir()
+	createTree(test, dirPath, map[string]string{"a.txt": "a", "b.txt": "b NAME", "c.txt": "c", "src.txt": "src"})
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dirPath, "a.txt"), modTime, modTime); err != nil {
+		test.Fatal(err)
+	}
+	failure := errors.New("step failed")
+
+	err := file.WithTransaction(func(tx *file.Transaction) error {
+		if err := tx.WriteFile(filepath.Join(dirPath, "a.txt"), "changed", true); err != nil {
+			return err
+		}
+		if err := tx.WriteFile(filepath.Join(dirPath, "a.txt"), "changed again", false); err != nil {
+			return err
+		}
+		if err := tx.ReplaceFileContent(filepath.Join(dirPath, "b.txt"), "NAME", "go-utils"); err != nil {
+			return err
+		}
+		if err := tx.RemoveFile(filepath.Join(dirPath, "c.txt")); err != nil {
+			return err
+		}
+		if err := tx.CopyFile(filepath.Join(dirPath, "src.txt"), filepath.Join(dirPath, "new.txt")); err != nil {
+			return err
+		}
+		return failure
+	})
+
+	if !errors.Is(err, failure) {
+		test.Errorf("Expected the step's error but got %v", err)
+	}
+	expected := []string{"a.txt", "b.txt", "c.txt", "src.txt"}
+	if result := listTree(test, dirPath); !reflect.DeepEqual(result, expected) {
+		test.Errorf("Expected %v but got %v", expected, result)
+	}
+	for name, content := range map[string]string{"a.txt": "a", "b.txt": "b NAME", "c.txt": "c"} {
+		if result := file.ReadFile(filepath.Join(dirPath, name)); result != content {
+			test.Errorf("Expected %s to be restored to %q but got %q", name, content, result)
+		}
+	}
+	aInfo, err := os.Stat(filepath.Join(dirPath, "a.txt"))
+	if err != nil {
+		test.Fatal(err)
+	}
+	if aInfo.Mode().Perm() != 0644 || !aInfo.ModTime().Equal(modTime) {
+		test.Errorf("Expected a.txt's mode and time to be restored but got %v, %v", aInfo.Mode(), aInfo.ModTime())
+	}
+}
+
+func TestWithTransactionCommit(test *testing.T) {
+	dirPath := test.TempDir()
+	filePath := filepath.Join(dirPath, "file.txt")
+	file.WriteFile(filePath, "old", false)
+
+	var tx *file.Transaction
+	err := file.WithTransaction(func(transaction *file.Transaction) error {
+		tx = transaction
+		return tx.WriteFile(filePath, "new", false)
+	})
+
+	if err != nil || file.ReadFile(filePath) != "new" {
+		test.Errorf("Expected the change to be kept but got %q, error %v", file.ReadFile(filePath), err)
+	}
+	if err = tx.WriteFile(filePath, "late", false); !errors.Is(err, file.ErrTransactionDone) {
+		test.Errorf("Expected ErrTransactionDone but got %v", err)
+	}
+	if err = tx.Rollback(); !errors.Is(err, file.ErrTransactionDone) {
+		test.Errorf("Expected ErrTransactionDone but got %v", err)
+	}
+}
+
+func TestWithTransactionPanic(test *testing.T) {
+	filePath := filepath.Join(test.TempDir(), "file.txt")
+	file.WriteFile(filePath, "old", false)
+
+	defer func() {
+		if recovered := recover(); recovered != "boom" {
+			test.Errorf("Expected the panic to be passed on but got %v", recovered)
+		}
+		if result := file.ReadFile(filePath); result != "old" {
+			test.Errorf("Expected the file to be rolled back but got %q", result)
+		}
+	}()
+	_ = file.WithTransaction(func(tx *file.Transaction) error {
+		if err := tx.WriteFile(filePath, "new", false); err != nil {
+			return err
+		}
+		panic("boom")
+	})
+}
+
+func TestTransactionTrack(test *testing.T) {
+	dirPath := test.TempDir()
+	filePath := filepath.Join(dirPath, "file.txt")
+	file.WriteFile(filePath, "old", false)
+
+	tx, err := file.NewTransaction()
+	if err != nil {
+		test.Fatal(err)
+	}
+	if err = tx.Track(filePath); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	file.WriteFile(filePath, "changed outside", false)
+	if err = tx.Track(dirPath); err == nil {
+		test.Errorf("Expected an error tracking a dir")
+	}
+
+	if err = tx.Rollback(); err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+	if result := file.ReadFile(filePath); result != "old" {
+		test.Errorf("Expected %q but got %q", "old", result)
+	}
+}
@@ -0,0 +1,60 @@
+package file
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// walkFiltered walks the tree below dirPath in lexical order without following symlinks. fn is called for every entry
+// selected by the filter (see Filter.selectsEntry), but not for dirPath itself. It gets the slash separated path
+// relative to dirPath, too, and may return filepath.SkipDir or filepath.SkipAll like for filepath.WalkDir.
+func walkFiltered(dirPath string, filter *Filter, fn func(entryPath, relPath string, dirEntry fs.DirEntry) error) error {
+	return filepath.WalkDir(dirPath, func(entryPath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entryPath == dirPath {
+			return nil
+		}
+		relPath, err := filepath.Rel(dirPath, entryPath)
+		if err != nil {
+			return err
+		}
+		return visitFiltered(entryPath, filepath.ToSlash(relPath), dirEntry, filter, fn)
+	})
+}
+
+// walkFilteredFS is walkFiltered for a tree within an fs.FS, where all paths are slash separated already
+func walkFilteredFS(fsys fs.FS, dirPath string, filter *Filter,
+	fn func(entryPath, relPath string, dirEntry fs.DirEntry) error) error {
+	return fs.WalkDir(fsys, dirPath, func(entryPath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entryPath == dirPath {
+			return nil
+		}
+		relPath := entryPath
+		if dirPath != "." {
+			relPath = strings.TrimPrefix(entryPath, dirPath+"/")
+		}
+		return visitFiltered(entryPath, path.Clean(relPath), dirEntry, filter, fn)
+	})
+}
+
+// visitFiltered applies the filter to a walked entry, skipping excluded dirs as a whole
+func visitFiltered(entryPath, relPath string, dirEntry fs.DirEntry, filter *Filter,
+	fn func(entryPath, relPath string, dirEntry fs.DirEntry) error) error {
+	if filter.excludes(relPath, dirEntry.IsDir()) {
+		if dirEntry.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+	if !filter.selectsEntry(relPath, dirEntry.IsDir()) {
+		return nil
+	}
+	return fn(entryPath, relPath, dirEntry)
+}
@@ -0,0 +1,366 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WatchEventKind tells what happened to a watched entry
+type WatchEventKind string
+
+const (
+	WatchCreate WatchEventKind = "create"
+	WatchModify WatchEventKind = "modify"
+	WatchDelete WatchEventKind = "delete"
+)
+
+// WatchEvent is a change found by Watch
+type WatchEvent struct {
+	Kind WatchEventKind
+	// Path is the path of the changed entry, RelPath the same relative to the watched dir and slash separated
+	Path    string
+	RelPath string
+	IsDir   bool
+}
+
+// WatchOptions configures Watch
+type WatchOptions struct {
+	// FilterOptions select the entries to watch, paths are matched relative to the watched dir
+	FilterOptions
+	// Interval is the time between two scans of the tree, zero means 500ms. With notifications, it's only the time
+	// waited for further changes if Debounce is zero.
+	Interval time.Duration
+	// Debounce is the time the tree has to stay unchanged before the changes are delivered, so that a burst of changes
+	// (like a generator writing many files) ends up in a single batch. Zero delivers the changes with the first scan
+	// not finding any new ones.
+	Debounce time.Duration
+	// Poll scans the tree regularly even if change notifications are available, e.g. for network mounts, where changes
+	// done by other hosts aren't notified
+	Poll bool
+	// OnError is called for scan errors other than the watched dir disappearing (which is reported as deletes)
+	OnError func(error)
+}
+
+// Watch watches the given dir recursively and sends batches of changes on the returned channel, until the context is
+// done and the channel is closed. Entries are compared by size, modification time and mode. On Linux, inotify tells
+// which entries to check, with a watch on every dir. Elsewhere, with Poll set, or if inotify isn't available (like when
+// the limit of watches is reached), the tree is scanned regularly instead, which works on every OS and filesystem. The
+// watcher switches to scanning, too, if the watched dir itself is deleted or moved. A batch holds the net changes of a
+// burst sorted by path: a file created and deleted again within a burst doesn't show up at all. Dirs only report
+// creates and deletes, as their modification time changes with their content. Symlinks aren't followed.
+func Watch(ctx context.Context, dirPath string, options WatchOptions) (<-chan []WatchEvent, error) {
+	filter, err := NewFilter(options.FilterOptions)
+	if err != nil {
+		return nil, wrapError("watch", dirPath, err)
+	}
+	if options.Interval <= 0 {
+		options.Interval = 500 * time.Millisecond
+	}
+	watcher := &dirWatcher{dirPath: dirPath, filter: filter, options: options}
+	if !options.Poll {
+		// Without notifications, the tree is scanned
+		watcher.notifier, _ = newWatchNotifier(dirPath)
+	}
+	base, err := watcher.scan()
+	if err != nil {
+		if watcher.notifier != nil {
+			watcher.notifier.close()
+		}
+		return nil, wrapError("watch", dirPath, err)
+	}
+
+	events := make(chan []WatchEvent)
+	go watcher.run(ctx, base, events)
+	return events, nil
+}
+
+type dirWatcher struct {
+	dirPath string
+	filter  *Filter
+	options WatchOptions
+	// notifier tells which entries have changed, nil means scanning the tree regularly
+	notifier watchNotifier
+}
+
+// watchNotifier tells which entries may have changed, so that only those have to be checked instead of scanning the
+// whole tree. The changes channel is closed when the notifier can't go on, then scanning takes over.
+type watchNotifier interface {
+	// addDir starts watching the entries of a dir, failing to do so stops the notifier
+	addDir(relPath string)
+	changes() <-chan []watchChange
+	close()
+}
+
+// watchChange is an entry which may have changed, "." with tree set meaning all of them
+type watchChange struct {
+	relPath string
+	// tree tells that the entry may have been replaced as a whole, so that everything below it has to be checked, too
+	tree bool
+}
+
+// watchedEntry is the state of an entry as far as change detection is concerned
+type watchedEntry struct {
+	isDir   bool
+	size    int64
+	modTime time.Time
+	mode    fs.FileMode
+}
+
+func (w *dirWatcher) run(ctx context.Context, base map[string]watchedEntry, events chan<- []WatchEvent) {
+	defer close(events)
+	if w.notifier != nil {
+		var done bool
+		if base, done = w.runNotified(ctx, base, events); done {
+			return
+		}
+		w.notifier = nil
+	}
+
+	ticker := time.NewTicker(w.options.Interval)
+	defer ticker.Stop()
+
+	last := base
+	var lastChange time.Time
+	pending := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := w.scan()
+		if errors.Is(err, fs.ErrNotExist) {
+			current, err = map[string]watchedEntry{}, nil
+		}
+		if err != nil {
+			if w.options.OnError != nil {
+				w.options.OnError(wrapError("watch", w.dirPath, err))
+			}
+			continue
+		}
+		if len(diffWatchedEntries(last, current)) > 0 {
+			last, lastChange, pending = current, time.Now(), true
+			continue
+		}
+		if !pending || time.Since(lastChange) < w.options.Debounce {
+			continue
+		}
+
+		pending = false
+		batch := w.events(diffWatchedEntries(base, last), base, last)
+		base = last
+		if len(batch) == 0 {
+			continue
+		}
+		select {
+		case events <- batch:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runNotified keeps the state up-to-date by checking the notified entries and delivers the changes once no further
+// notifications arrive. Returns the state delivered last and whether the context is done - otherwise the notifier
+// stopped and scanning has to take over.
+func (w *dirWatcher) runNotified(ctx context.Context, base map[string]watchedEntry,
+	events chan<- []WatchEvent) (map[string]watchedEntry, bool) {
+	defer w.notifier.close()
+	quietTime := w.options.Debounce
+	if quietTime <= 0 {
+		quietTime = w.options.Interval
+	}
+	timer := time.NewTimer(quietTime)
+	timer.Stop()
+	defer timer.Stop()
+
+	last := maps.Clone(base)
+	for {
+		select {
+		case <-ctx.Done():
+			return base, true
+		case changes, ok := <-w.notifier.changes():
+			if !ok {
+				return base, false
+			}
+			for _, change := range changes {
+				w.refresh(last, change)
+			}
+			timer.Reset(quietTime)
+			continue
+		case <-timer.C:
+		}
+
+		batch := w.events(diffWatchedEntries(base, last), base, last)
+		base, last = last, maps.Clone(last)
+		if len(batch) == 0 {
+			continue
+		}
+		select {
+		case events <- batch:
+		case <-ctx.Done():
+			return base, true
+		}
+	}
+}
+
+// refresh updates the state of a notified entry, rescanning everything below it if it may have been replaced
+func (w *dirWatcher) refresh(entries map[string]watchedEntry, change watchChange) {
+	if change.relPath == "." {
+		current, err := w.scan()
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			w.reportError(err)
+			return
+		}
+		clear(entries)
+		maps.Copy(entries, current)
+		return
+	}
+
+	for relPath := range entries {
+		if relPath == change.relPath || (change.tree && strings.HasPrefix(relPath, change.relPath+"/")) {
+			delete(entries, relPath)
+		}
+	}
+	info, err := os.Lstat(filepath.Join(w.dirPath, filepath.FromSlash(change.relPath)))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			w.reportError(err)
+		}
+		return
+	}
+	if w.filter.excludes(change.relPath, info.IsDir()) {
+		return
+	}
+	if w.filter.selectsEntry(change.relPath, info.IsDir()) {
+		entries[change.relPath] = newWatchedEntry(info)
+	}
+	if change.tree && info.IsDir() {
+		if err = w.scanInto(entries, change.relPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			w.reportError(err)
+		}
+	}
+}
+
+func (w *dirWatcher) reportError(err error) {
+	if w.options.OnError != nil {
+		w.options.OnError(wrapError("watch", w.dirPath, err))
+	}
+}
+
+// scan collects the state of all selected entries below the dir
+func (w *dirWatcher) scan() (map[string]watchedEntry, error) {
+	entries := make(map[string]watchedEntry)
+	err := w.scanInto(entries, ".")
+	if err == nil {
+		var dirInfo os.FileInfo
+		if dirInfo, err = os.Stat(w.dirPath); err == nil && !dirInfo.IsDir() {
+			err = errors.New("not a directory")
+		}
+	}
+	return entries, err
+}
+
+// scanInto adds the state of all selected entries below the given dir. With a notifier, all dirs walked are watched,
+// before their entries are read.
+func (w *dirWatcher) scanInto(entries map[string]watchedEntry, dirRelPath string) error {
+	if w.notifier != nil {
+		w.notifier.addDir(dirRelPath)
+	}
+	dirPath := filepath.Join(w.dirPath, filepath.FromSlash(dirRelPath))
+	return filepath.WalkDir(dirPath, func(entryPath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			if entryPath != dirPath && errors.Is(err, fs.ErrNotExist) {
+				// Deleted while scanning, the next scan or notification will tell
+				return nil
+			}
+			return err
+		}
+		if entryPath == dirPath {
+			return nil
+		}
+		relPath, err := filepath.Rel(w.dirPath, entryPath)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		// Like walkFiltered, but dirs which aren't selected themselves are watched, too
+		if w.filter.excludes(relPath, dirEntry.IsDir()) {
+			if dirEntry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if dirEntry.IsDir() && w.notifier != nil {
+			w.notifier.addDir(relPath)
+		}
+		if !w.filter.selectsEntry(relPath, dirEntry.IsDir()) {
+			return nil
+		}
+		info, err := dirEntry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		entries[relPath] = newWatchedEntry(info)
+		return nil
+	})
+}
+
+func newWatchedEntry(info fs.FileInfo) watchedEntry {
+	return watchedEntry{isDir: info.IsDir(), size: info.Size(), modTime: info.ModTime(), mode: info.Mode()}
+}
+
+// diffWatchedEntries returns the sorted paths of all entries differing between the two states
+func diffWatchedEntries(old, new map[string]watchedEntry) []string {
+	var relPaths []string
+	for relPath, oldEntry := range old {
+		newEntry, exists := new[relPath]
+		if !exists || newEntry.isDir != oldEntry.isDir || (!newEntry.isDir && (newEntry.size != oldEntry.size ||
+			!newEntry.modTime.Equal(oldEntry.modTime) || newEntry.mode != oldEntry.mode)) {
+			relPaths = append(relPaths, relPath)
+		}
+	}
+	for relPath := range new {
+		if _, exists := old[relPath]; !exists {
+			relPaths = append(relPaths, relPath)
+		}
+	}
+	sort.Strings(relPaths)
+	return relPaths
+}
+
+// events turns the changed paths into events, an entry changing between file and dir is deleted and created again
+func (w *dirWatcher) events(relPaths []string, old, new map[string]watchedEntry) []WatchEvent {
+	var events []WatchEvent
+	newEvent := func(kind WatchEventKind, relPath string, isDir bool) WatchEvent {
+		return WatchEvent{Kind: kind, Path: filepath.Join(w.dirPath, filepath.FromSlash(relPath)), RelPath: relPath,
+			IsDir: isDir}
+	}
+	for _, relPath := range relPaths {
+		oldEntry, oldExists := old[relPath]
+		newEntry, newExists := new[relPath]
+		switch {
+		case !oldExists:
+			events = append(events, newEvent(WatchCreate, relPath, newEntry.isDir))
+		case !newExists:
+			events = append(events, newEvent(WatchDelete, relPath, oldEntry.isDir))
+		case oldEntry.isDir != newEntry.isDir:
+			events = append(events, newEvent(WatchDelete, relPath, oldEntry.isDir),
+				newEvent(WatchCreate, relPath, newEntry.isDir))
+		default:
+			events = append(events, newEvent(WatchModify, relPath, newEntry.isDir))
+		}
+	}
+	return events
+}
@@ -0,0 +1,175 @@
+package file
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// inotifyMask selects the events telling that entries of a watched dir have changed, or the dir itself is gone
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY | syscall.IN_ATTRIB |
+	syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF |
+	syscall.IN_MOVE_SELF | syscall.IN_ONLYDIR | syscall.IN_DONT_FOLLOW | syscall.IN_EXCL_UNLINK
+
+// inotifyNotifier watches every dir of the tree with inotify
+type inotifyNotifier struct {
+	dirPath string
+	fd      int
+	// file reads the events through the runtime's poller, so that closing it ends a pending read
+	file          *os.File
+	changeChannel chan []watchChange
+	done          chan struct{}
+	mutex         sync.Mutex
+	closed        bool
+	// dirRelPaths maps the watch descriptors to the dirs they watch
+	dirRelPaths map[int]string
+}
+
+// newWatchNotifier starts an inotify instance, whose watches are added while scanning the tree
+func newWatchNotifier(dirPath string) (watchNotifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	notifier := &inotifyNotifier{
+		dirPath:       dirPath,
+		fd:            fd,
+		file:          os.NewFile(uintptr(fd), "inotify"),
+		changeChannel: make(chan []watchChange),
+		done:          make(chan struct{}),
+		dirRelPaths:   make(map[int]string),
+	}
+	go notifier.read()
+	return notifier, nil
+}
+
+// addDir watches a dir, one which is gone already doesn't need to be watched. Any other error (like the limit of
+// watches being reached) stops the notifier.
+func (n *inotifyNotifier) addDir(relPath string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.closed {
+		return
+	}
+	dirPath := filepath.Join(n.dirPath, filepath.FromSlash(relPath))
+	wd, err := syscall.InotifyAddWatch(n.fd, dirPath, inotifyMask)
+	if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ENOTDIR) {
+		return
+	}
+	if err != nil {
+		n.closeLocked()
+		return
+	}
+	n.dirRelPaths[wd] = relPath
+}
+
+func (n *inotifyNotifier) changes() <-chan []watchChange {
+	return n.changeChannel
+}
+
+func (n *inotifyNotifier) close() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.closeLocked()
+}
+
+func (n *inotifyNotifier) closeLocked() {
+	if n.closed {
+		return
+	}
+	n.closed = true
+	close(n.done)
+	_ = n.file.Close()
+}
+
+// read delivers the changes of each read until the notifier is closed or the watched dir is gone
+func (n *inotifyNotifier) read() {
+	defer close(n.changeChannel)
+	buffer := make([]byte, 256*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		count, err := n.file.Read(buffer)
+		if err != nil {
+			return
+		}
+		changes, rootGone := n.parse(buffer[:count])
+		if len(changes) > 0 {
+			select {
+			case n.changeChannel <- changes:
+			case <-n.done:
+				return
+			}
+		}
+		if rootGone {
+			n.close()
+			return
+		}
+	}
+}
+
+// parse turns the events into changes, each entry only once. Entries created, deleted or moved may be whole trees.
+func (n *inotifyNotifier) parse(buffer []byte) ([]watchChange, bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	var changes []watchChange
+	changeIndexes := make(map[string]int)
+	addChange := func(change watchChange) {
+		if i, ok := changeIndexes[change.relPath]; ok {
+			changes[i].tree = changes[i].tree || change.tree
+			return
+		}
+		changeIndexes[change.relPath] = len(changes)
+		changes = append(changes, change)
+	}
+
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buffer); {
+		wd := int(int32(binary.NativeEndian.Uint32(buffer[offset:])))
+		mask := binary.NativeEndian.Uint32(buffer[offset+4:])
+		nameLength := int(binary.NativeEndian.Uint32(buffer[offset+12:]))
+		nameOffset := offset + syscall.SizeofInotifyEvent
+		name := strings.TrimRight(string(buffer[nameOffset:nameOffset+nameLength]), "\x00")
+		offset = nameOffset + nameLength
+
+		if mask&syscall.IN_Q_OVERFLOW != 0 {
+			addChange(watchChange{relPath: ".", tree: true})
+			continue
+		}
+		dirRelPath, known := n.dirRelPaths[wd]
+		if !known {
+			continue
+		}
+		if name == "" {
+			// Events of a dir itself are reported by its parent's watch as well, except for the watched dir
+			if dirRelPath == "." && mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF|syscall.IN_IGNORED) != 0 {
+				return changes, true
+			}
+			if mask&syscall.IN_IGNORED != 0 {
+				delete(n.dirRelPaths, wd)
+			}
+			continue
+		}
+
+		relPath := path.Join(dirRelPath, name)
+		tree := mask&(syscall.IN_CREATE|syscall.IN_DELETE|syscall.IN_MOVED_FROM|syscall.IN_MOVED_TO) != 0
+		if mask&syscall.IN_ISDIR != 0 && mask&syscall.IN_MOVED_FROM != 0 {
+			// The watches of a moved dir would keep reporting its old paths, so they are added again for the new ones
+			n.removeWatches(relPath)
+		}
+		addChange(watchChange{relPath: relPath, tree: tree})
+	}
+	return changes, false
+}
+
+// removeWatches removes the watches of a dir and all dirs below
+func (n *inotifyNotifier) removeWatches(dirRelPath string) {
+	for wd, relPath := range n.dirRelPaths {
+		if relPath == dirRelPath || strings.HasPrefix(relPath, dirRelPath+"/") {
+			_, _ = syscall.InotifyRmWatch(n.fd, uint32(wd))
+			delete(n.dirRelPaths, wd)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package file_test
+
+import (
+	"context"
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatchInotify(test *testing.T) {
+	// Scanning once an hour would never deliver anything in time
+	testWatch(test, file.WatchOptions{Interval: time.Hour})
+}
+
+func TestWatchInotifyFallback(test *testing.T) {
+	dirPath := filepath.Join(test.TempDir(), "watched")
+	createTree(test, dirPath, map[string]string{"a.txt": "a"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := file.Watch(ctx, dirPath, file.WatchOptions{Interval: 10 * time.Millisecond, Debounce: 50 * time.Millisecond})
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Once the watched dir is gone, scanning takes over and notices it coming back
+	if err = os.RemoveAll(dirPath); err != nil {
+		test.Fatal(err)
+	}
+	expected := []string{"delete a.txt"}
+	if batch := receiveBatch(test, events); !reflect.DeepEqual(batch, expected) {
+		test.Errorf("Expected %v, got %v", expected, batch)
+	}
+	createTree(test, dirPath, map[string]string{"b.txt": "b"})
+	expected = []string{"create b.txt"}
+	if batch := receiveBatch(test, events); !reflect.DeepEqual(batch, expected) {
+		test.Errorf("Expected %v, got %v", expected, batch)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package file
+
+import "errors"
+
+// newWatchNotifier has no notifications to offer, so Watch scans the tree
+func newWatchNotifier(dirPath string) (watchNotifier, error) {
+	return nil, errors.ErrUnsupported
+}
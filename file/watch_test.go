@@ -0,0 +1,110 @@
+package file_test
+
+import (
+	"context"
+	"github.com/investify-tech/go-utils/file"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// receiveBatch waits for the next batch of watch events, reduced to "kind relPath"
+func receiveBatch(t *testing.T, events <-chan []file.WatchEvent) []string {
+	t.Helper()
+	select {
+	case batch := <-events:
+		var result []string
+		for _, event := range batch {
+			result = append(result, string(event.Kind)+" "+event.RelPath)
+		}
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatal("No watch events received")
+		return nil
+	}
+}
+
+func TestWatch(test *testing.T) {
+	testWatch(test, file.WatchOptions{Interval: 10 * time.Millisecond, Poll: true})
+}
+
+// testWatch runs through the changes every backend has to detect, the options only select the backend
+func testWatch(test *testing.T, options file.WatchOptions) {
+	dirPath := test.TempDir()
+	createTree(test, dirPath, map[string]string{"existing.txt": "old", "gone.txt": "gone"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	options.FilterOptions = file.FilterOptions{Exclude: []string{"*.tmp"}}
+	options.Debounce = 50 * time.Millisecond
+	events, err := file.Watch(ctx, dirPath, options)
+	if err != nil {
+		test.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A burst of changes is delivered as one batch
+	createTree(test, dirPath, map[string]string{"sub/new.txt": "new", "ignored.tmp": "tmp", "existing.txt": "changed"})
+	if err = os.Remove(filepath.Join(dirPath, "gone.txt")); err != nil {
+		test.Fatal(err)
+	}
+	expected := []string{"modify existing.txt", "delete gone.txt", "create sub", "create sub/new.txt"}
+	if batch := receiveBatch(test, events); !reflect.DeepEqual(batch, expected) {
+		test.Errorf("Expected %v, got %v", expected, batch)
+	}
+
+	// New dirs are watched, too, also after being moved
+	createTree(test, dirPath, map[string]string{"sub/later.txt": "later"})
+	expected = []string{"create sub/later.txt"}
+	if batch := receiveBatch(test, events); !reflect.DeepEqual(batch, expected) {
+		test.Errorf("Expected %v, got %v", expected, batch)
+	}
+	if err = os.Rename(filepath.Join(dirPath, "sub"), filepath.Join(dirPath, "moved")); err != nil {
+		test.Fatal(err)
+	}
+	expected = []string{"create moved", "create moved/later.txt", "create moved/new.txt", "delete sub",
+		"delete sub/later.txt", "delete sub/new.txt"}
+	if batch := receiveBatch(test, events); !reflect.DeepEqual(batch, expected) {
+		test.Errorf("Expected %v, got %v", expected, batch)
+	}
+	if err = os.Rename(filepath.Join(dirPath, "moved"), filepath.Join(dirPath, "sub")); err != nil {
+		test.Fatal(err)
+	}
+	receiveBatch(test, events)
+	createTree(test, dirPath, map[string]string{"sub/later.txt": "changed"})
+	expected = []string{"modify sub/later.txt"}
+	if batch := receiveBatch(test, events); !reflect.DeepEqual(batch, expected) {
+		test.Errorf("Expected %v, got %v", expected, batch)
+	}
+
+	// Entries created and deleted again within a burst cancel out
+	createTree(test, dirPath, map[string]string{"short.txt": "short"})
+	time.Sleep(20 * time.Millisecond)
+	if err = os.Remove(filepath.Join(dirPath, "short.txt")); err != nil {
+		test.Fatal(err)
+	}
+	if err = os.RemoveAll(filepath.Join(dirPath, "sub")); err != nil {
+		test.Fatal(err)
+	}
+	expected = []string{"delete sub", "delete sub/later.txt", "delete sub/new.txt"}
+	if batch := receiveBatch(test, events); !reflect.DeepEqual(batch, expected) {
+		test.Errorf("Expected %v, got %v", expected, batch)
+	}
+
+	cancel()
+	select {
+	case _, open := <-events:
+		if open {
+			test.Errorf("Expected no more events")
+		}
+	case <-time.After(5 * time.Second):
+		test.Errorf("Expected the channel to be closed on cancellation")
+	}
+}
+
+func TestWatchMissingDir(test *testing.T) {
+	if _, err := file.Watch(context.Background(), filepath.Join(test.TempDir(), "missing"), file.WatchOptions{}); err == nil {
+		test.Errorf("Expected an error for a missing dir")
+	}
+}
@@ -0,0 +1,59 @@
+package file
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+)
+
+// copyXattrs copies all extended attributes from src to dst. A source file system without xattr support simply has
+// nothing to copy.
+func copyXattrs(srcPath, dstPath string) error {
+	names, err := listXattrs(srcPath)
+	if errors.Is(err, syscall.ENOTSUP) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		value, err := getXattr(srcPath, name)
+		if err != nil {
+			return err
+		}
+		if err = syscall.Setxattr(dstPath, name, value, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func listXattrs(path string) ([]string, error) {
+	size, err := syscall.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buffer := make([]byte, size)
+	if size, err = syscall.Listxattr(path, buffer); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range strings.Split(string(buffer[:size]), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func getXattr(path string, name string) ([]byte, error) {
+	size, err := syscall.Getxattr(path, name, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buffer := make([]byte, size)
+	if size, err = syscall.Getxattr(path, name, buffer); err != nil {
+		return nil, err
+	}
+	return buffer[:size], nil
+}
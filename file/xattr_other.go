@@ -0,0 +1,10 @@
+//go:build !linux
+
+package file
+
+import "errors"
+
+// copyXattrs is only implemented for Linux so far
+func copyXattrs(srcPath, dstPath string) error {
+	return errors.ErrUnsupported
+}